@@ -0,0 +1,261 @@
+package osc
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// ACLAction is the action of an ACL rule.
+type ACLAction int
+
+const (
+	// ACLAllow allows messages.
+	ACLAllow ACLAction = iota
+	// ACLDeny denies messages.
+	ACLDeny
+)
+
+// String implements the fmt.Stringer interface.
+func (a ACLAction) String() string {
+	if a == ACLDeny {
+		return "deny"
+	}
+	return "allow"
+}
+
+// ACLRule allows or denies the messages of Source to Address.
+type ACLRule struct {
+	Action ACLAction
+	// Source is a network in CIDR notation (e.g. "10.0.2.0/24") or an IP
+	// address. Empty means all sources.
+	Source string
+	// Address is an OSC address pattern (e.g. "/cue/*") of the controlled
+	// addresses. Empty means all addresses.
+	Address string
+}
+
+// aclRule is a parsed ACLRule.
+type aclRule struct {
+	ACLRule
+	network *net.IPNet
+	regex   *regexp.Regexp
+	hits    atomic.Uint64
+}
+
+// ACLStats holds the counters of an ACL.
+type ACLStats struct {
+	// Allowed is the number of allowed messages.
+	Allowed uint64
+	// Denied is the number of denied messages.
+	Denied uint64
+	// Hits is the number of messages matched by every rule (see Rules).
+	Hits []uint64
+}
+
+// ACL is a Dispatcher, that allows or denies received messages by the source
+// address and the OSC address before they are dispatched by Next. The rules
+// are checked in order, the first matching rule decides. If no rule matches,
+// Default decides. Denied messages are removed from bundles.
+//
+// Messages with an address pattern (e.g. "/*") could reach any handler, so
+// they match every deny rule with an Address, but no allow rule with an
+// Address.
+//
+// The rules can be changed while the ACL is in use.
+type ACL struct {
+	// Next dispatches the allowed messages.
+	Next Dispatcher
+	// Default is the action, if no rule matches.
+	Default ACLAction
+	// Denied is called for every denied message.
+	Denied func(msg *Message, addr net.Addr)
+
+	mutex   sync.RWMutex
+	rules   []*aclRule
+	allowed atomic.Uint64
+	denied  atomic.Uint64
+}
+
+// NewACL returns an ACL, that dispatches the allowed messages with next.
+func NewACL(next Dispatcher, def ACLAction) *ACL {
+	return &ACL{Next: next, Default: def}
+}
+
+// Allow appends a rule, that allows the messages of source to address.
+func (a *ACL) Allow(source, address string) error {
+	return a.AddRule(ACLRule{Action: ACLAllow, Source: source, Address: address})
+}
+
+// Deny appends a rule, that denies the messages of source to address.
+func (a *ACL) Deny(source, address string) error {
+	return a.AddRule(ACLRule{Action: ACLDeny, Source: source, Address: address})
+}
+
+// AddRule appends a rule.
+func (a *ACL) AddRule(rule ACLRule) error {
+	r, err := parseACLRule(rule)
+	if err != nil {
+		return err
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.rules = append(a.rules, r)
+	return nil
+}
+
+// SetRules replaces all rules and resets their hit counters.
+func (a *ACL) SetRules(rules ...ACLRule) error {
+	parsed := make([]*aclRule, len(rules))
+	for i, rule := range rules {
+		r, err := parseACLRule(rule)
+		if err != nil {
+			return err
+		}
+		parsed[i] = r
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.rules = parsed
+	return nil
+}
+
+// Rules returns all rules.
+func (a *ACL) Rules() []ACLRule {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	rules := make([]ACLRule, len(a.rules))
+	for i, r := range a.rules {
+		rules[i] = r.ACLRule
+	}
+	return rules
+}
+
+// Stats returns the counters of the ACL.
+func (a *ACL) Stats() ACLStats {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	stats := ACLStats{
+		Allowed: a.allowed.Load(),
+		Denied:  a.denied.Load(),
+		Hits:    make([]uint64, len(a.rules)),
+	}
+	for i, r := range a.rules {
+		stats.Hits[i] = r.hits.Load()
+	}
+	return stats
+}
+
+// parseACLRule parses the source and the address of rule.
+func parseACLRule(rule ACLRule) (*aclRule, error) {
+	r := &aclRule{ACLRule: rule}
+
+	if rule.Source != "" {
+		network, err := parseNetwork(rule.Source)
+		if err != nil {
+			return nil, err
+		}
+		r.network = network
+	}
+
+	if rule.Address != "" {
+		if !strings.HasPrefix(rule.Address, "/") {
+			return nil, fmt.Errorf("%w: invalid ACL address %q", ErrorOscAddressFormat, rule.Address)
+		}
+		regex, err := getRegEx(rule.Address)
+		if err != nil {
+			return nil, err
+		}
+		r.regex = regex
+	}
+
+	return r, nil
+}
+
+// match returns true if the rule matches the message to address from ip.
+func (r *aclRule) match(ip net.IP, address string) bool {
+	if r.network != nil && (ip == nil || !r.network.Contains(ip)) {
+		return false
+	}
+	if r.regex == nil {
+		return true
+	}
+	if hasPattern(address) {
+		return r.Action == ACLDeny
+	}
+	return r.regex.MatchString(address)
+}
+
+// Check returns true if the message to the OSC address address from addr is
+// allowed. The counters aren't changed.
+func (a *ACL) Check(addr net.Addr, address string) bool {
+	return a.check(addr, address, false)
+}
+
+// check returns true if the message is allowed and counts the rule hit.
+func (a *ACL) check(addr net.Addr, address string, count bool) bool {
+	ip := addrIP(addr)
+
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	for _, r := range a.rules {
+		if r.match(ip, address) {
+			if count {
+				r.hits.Add(1)
+			}
+			return r.Action == ACLAllow
+		}
+	}
+	return a.Default == ACLAllow
+}
+
+// Dispatch implements the Dispatcher interface.
+func (a *ACL) Dispatch(packet Packet, addr net.Addr) error {
+	packet = a.filter(packet, addr)
+	if packet == nil || a.Next == nil {
+		return nil
+	}
+	return a.Next.Dispatch(packet, addr)
+}
+
+// filter returns the allowed messages of packet or nil.
+func (a *ACL) filter(packet Packet, addr net.Addr) Packet {
+	switch p := packet.(type) {
+	case *Message:
+		if a.check(addr, p.Address, true) {
+			a.allowed.Add(1)
+			return p
+		}
+		a.denied.Add(1)
+		if a.Denied != nil {
+			a.Denied(p, addr)
+		}
+		return nil
+
+	case *Bundle:
+		b := &Bundle{Timetag: p.Timetag}
+		for _, msg := range p.Messages {
+			if a.filter(msg, addr) != nil {
+				b.Messages = append(b.Messages, msg)
+			}
+		}
+		for _, bundle := range p.Bundles {
+			if f := a.filter(bundle, addr); f != nil {
+				b.Bundles = append(b.Bundles, f.(*Bundle))
+			}
+		}
+		if len(b.Messages) == 0 && len(b.Bundles) == 0 {
+			return nil
+		}
+		return b
+	}
+	return packet
+}
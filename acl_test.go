@@ -0,0 +1,127 @@
+package osc_test
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"bekuba.de/go-osc"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestACL(t *testing.T) {
+	var handled []string
+	d := osc.NewStandardDispatcher()
+	for _, addr := range []string{"/cue/go", "/cue/stop", "/fader/1"} {
+		err := d.AddMsgHandler(addr, func(msg *osc.Message) {
+			handled = append(handled, addr)
+		})
+		assert.NoError(t, err)
+	}
+
+	var denied []string
+	acl := osc.NewACL(d, osc.ACLAllow)
+	acl.Denied = func(msg *osc.Message, addr net.Addr) {
+		denied = append(denied, addr.String()+" "+msg.Address)
+	}
+
+	// only 10.0.2.0/24 may send /cue/*
+	assert.NoError(t, acl.Allow("10.0.2.0/24", "/cue/*"))
+	assert.NoError(t, acl.Deny("", "/cue/*"))
+	// a misbehaving tablet
+	assert.NoError(t, acl.Deny("10.0.3.66", ""))
+
+	console := &net.UDPAddr{IP: net.IPv4(10, 0, 2, 5), Port: 9000}
+	tablet := &net.UDPAddr{IP: net.IPv4(10, 0, 3, 5), Port: 9000}
+	broken := &net.UDPAddr{IP: net.IPv4(10, 0, 3, 66), Port: 9000}
+
+	for _, tt := range []struct {
+		addr    net.Addr
+		address string
+		allowed bool
+	}{
+		{console, "/cue/go", true},
+		{console, "/fader/1", true},
+		{tablet, "/cue/go", false},
+		{tablet, "/fader/1", true},
+		{broken, "/fader/1", false},
+		// IPv4-mapped
+		{&net.UDPAddr{IP: net.ParseIP("::ffff:10.0.2.5"), Port: 9000}, "/cue/go", true},
+		// address patterns
+		{tablet, "/*", false},
+		{tablet, "/fader/?", false},
+		{console, "/cue/*", false},
+		// TLS peers
+		{&osc.TLSAddr{Addr: &net.TCPAddr{IP: net.IPv4(10, 0, 2, 7), Port: 443}}, "/cue/stop", true},
+		// Unix domain sockets only match rules without source
+		{&net.UnixAddr{Name: "/run/osc.sock", Net: "unixgram"}, "/cue/go", false},
+		{&net.UnixAddr{Name: "/run/osc.sock", Net: "unixgram"}, "/fader/1", true},
+	} {
+		assert.Equal(t, tt.allowed, acl.Check(tt.addr, tt.address), "%v %s", tt.addr, tt.address)
+	}
+
+	assert.NoError(t, acl.Dispatch(osc.NewMessage("/cue/go"), console))
+	assert.NoError(t, acl.Dispatch(osc.NewMessage("/cue/stop"), tablet))
+	assert.NoError(t, acl.Dispatch(osc.NewMessage("/fader/1"), tablet))
+	assert.Equal(t, []string{"/cue/go", "/fader/1"}, handled)
+	assert.Equal(t, []string{"10.0.3.5:9000 /cue/stop"}, denied)
+
+	stats := acl.Stats()
+	assert.Equal(t, uint64(2), stats.Allowed)
+	assert.Equal(t, uint64(1), stats.Denied)
+	assert.Equal(t, []uint64{1, 1, 0}, stats.Hits)
+
+	// denied messages are removed from bundles
+	handled = nil
+	bundle := osc.NewBundle(time.Now())
+	assert.NoError(t, bundle.Append(osc.NewMessage("/cue/go")))
+	assert.NoError(t, bundle.Append(osc.NewMessage("/fader/1")))
+	assert.NoError(t, acl.Dispatch(bundle, tablet))
+	assert.Equal(t, []string{"/fader/1"}, handled)
+
+	// rules changed at runtime
+	assert.NoError(t, acl.SetRules(osc.ACLRule{Action: osc.ACLAllow, Source: "10.0.3.0/24"}))
+	acl.Default = osc.ACLDeny
+	assert.True(t, acl.Check(tablet, "/cue/go"))
+	assert.False(t, acl.Check(console, "/fader/1"))
+	assert.Equal(t, []osc.ACLRule{{Action: osc.ACLAllow, Source: "10.0.3.0/24"}}, acl.Rules())
+	assert.Equal(t, []uint64{0}, acl.Stats().Hits)
+
+	// invalid rules
+	assert.ErrorIs(t, acl.Allow("10.0.2.0/33", ""), osc.ErrorOscAddressFormat)
+	assert.ErrorIs(t, acl.Allow("", "cue"), osc.ErrorOscAddressFormat)
+}
+
+func TestACLNode(t *testing.T) {
+	server, err := osc.NewNode("127.0.0.1:0")
+	assert.NoError(t, err)
+	defer server.Close()
+
+	received := make(chan string, 10)
+	d := osc.NewStandardDispatcher()
+	err = d.AddMsgHandler("*", func(msg *osc.Message) {
+		received <- msg.Address
+	})
+	assert.NoError(t, err)
+
+	acl := osc.NewACL(d, osc.ACLAllow)
+	assert.NoError(t, acl.Deny("127.0.0.0/8", "/cue/*"))
+	go server.ListenAndServe(acl)
+
+	client, err := osc.NewNode("127.0.0.1:0")
+	assert.NoError(t, err)
+	defer client.Close()
+
+	addr := server.Conn().LocalAddr().String()
+	assert.NoError(t, client.SendMsgTo(addr, "/cue/go"))
+	assert.NoError(t, client.SendMsgTo(addr, "/fader/1"))
+
+	select {
+	case address := <-received:
+		assert.Equal(t, "/fader/1", address)
+	case <-time.After(time.Second):
+		t.Fatal("timeout")
+	}
+	assert.Equal(t, uint64(1), acl.Stats().Denied)
+}
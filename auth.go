@@ -0,0 +1,246 @@
+package osc
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DefaultAuthMaxAge is the maximum age of a received signed packet, if
+// Authenticator.MaxAge is not set.
+const DefaultAuthMaxAge = 10 * time.Second
+
+const (
+	// authAddress is the OSC address of the signature message.
+	authAddress = "/hmac-sha256"
+	// authNonceSize is the size of the nonce in bytes.
+	authNonceSize = 16
+)
+
+// Authenticator signs sent OSC packets and verifies received OSC packets with
+// HMAC-SHA256 and a shared key (see Node.Auth).
+//
+// A signed packet is an OSC bundle with the send time as timetag. Its first
+// element is the message
+//
+//	/hmac-sha256 ,bb <nonce (16 bytes)> <signature (32 bytes)>
+//
+// and its second element is the original packet. The signature is the
+// HMAC-SHA256 of the timetag (8 bytes), the nonce and the original packet.
+// Received packets are rejected, if the signature is invalid, if they are
+// older than MaxAge or if the nonce was received before (replay).
+type Authenticator struct {
+	// Key is the shared key of all peers without own key (see SetPeerKey).
+	// If it is nil, the packets of these peers are neither signed nor
+	// verified.
+	Key []byte
+	// MaxAge is the maximum age of received packets, including the clock
+	// difference of the peers. Zero means DefaultAuthMaxAge.
+	MaxAge time.Duration
+	// Rejected is called for every received packet, that is rejected.
+	Rejected func(addr net.Addr, err error)
+
+	mutex     sync.Mutex
+	peerKeys  map[string][]byte
+	nonces    map[string]time.Time
+	lastPurge time.Time
+}
+
+// NewAuthenticator returns an Authenticator with the shared key of all peers.
+func NewAuthenticator(key []byte) *Authenticator {
+	return &Authenticator{Key: key}
+}
+
+// SetPeerKey sets the key of the peer addr, which is an IP address (all
+// ports), an IP address and port or the path of a Unix domain socket. A nil
+// key removes the key of the peer.
+func (a *Authenticator) SetPeerKey(addr string, key []byte) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if a.peerKeys == nil {
+		a.peerKeys = make(map[string][]byte)
+	}
+
+	addr = normalizeAuthAddr(addr)
+	if key == nil {
+		delete(a.peerKeys, addr)
+	} else {
+		a.peerKeys[addr] = key
+	}
+}
+
+// normalizeAuthAddr returns addr with a normalized IP address.
+func normalizeAuthAddr(addr string) string {
+	if ip := net.ParseIP(addr); ip != nil {
+		return ip.String()
+	}
+	if host, port, err := net.SplitHostPort(addr); err == nil {
+		if ip := net.ParseIP(host); ip != nil {
+			return net.JoinHostPort(ip.String(), port)
+		}
+	}
+	return addr
+}
+
+// key returns the key of the peer addr or nil.
+func (a *Authenticator) key(addr net.Addr) []byte {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if udpAddr, ok := addr.(*net.UDPAddr); ok {
+		ip := udpAddr.IP.String()
+		if key, ok := a.peerKeys[net.JoinHostPort(ip, strconv.Itoa(udpAddr.Port))]; ok {
+			return key
+		}
+		if key, ok := a.peerKeys[ip]; ok {
+			return key
+		}
+	} else if addr != nil {
+		if key, ok := a.peerKeys[addr.String()]; ok {
+			return key
+		}
+	}
+	return a.Key
+}
+
+// maxAge returns the maximum age of received packets.
+func (a *Authenticator) maxAge() time.Duration {
+	if a.MaxAge > 0 {
+		return a.MaxAge
+	}
+	return DefaultAuthMaxAge
+}
+
+// Overhead returns the number of bytes, that signing adds to a packet. Packets
+// to peers without key aren't signed and have no overhead.
+func (a *Authenticator) Overhead() int {
+	auth, _ := NewMessage(authAddress, make([]byte, authNonceSize), make([]byte, sha256.Size)).MarshalBinary()
+	// bundle tag, timetag and the sizes of both elements
+	return len(bundleTagString) + 1 + 8 + 4 + len(auth) + 4
+}
+
+// sign returns the signed packet of the marshaled OSC packet data for the
+// peer addr, or data, if the peer has no key.
+func (a *Authenticator) sign(data []byte, addr net.Addr) ([]byte, error) {
+	key := a.key(addr)
+	if key == nil {
+		return data, nil
+	}
+
+	nonce := make([]byte, authNonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	timetag := binary.BigEndian.AppendUint64(nil, uint64(NewTimetag()))
+
+	auth, err := NewMessage(authAddress, nonce, authSignature(key, timetag, nonce, data)).MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+
+	signed := make([]byte, 0, len(bundleTagString)+1+8+4+len(auth)+4+len(data))
+	signed = append(signed, bundleTagString...)
+	signed = append(signed, 0)
+	signed = append(signed, timetag...)
+	signed = binary.BigEndian.AppendUint32(signed, uint32(len(auth)))
+	signed = append(signed, auth...)
+	signed = binary.BigEndian.AppendUint32(signed, uint32(len(data)))
+	signed = append(signed, data...)
+	return signed, nil
+}
+
+// authSignature returns the HMAC-SHA256 of timetag, nonce and data.
+func authSignature(key, timetag, nonce, data []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(timetag)
+	mac.Write(nonce)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+// verify verifies the signed packet data of the peer addr and returns the
+// original packet. If the peer has no key, data is returned.
+func (a *Authenticator) verify(data []byte, addr net.Addr) ([]byte, error) {
+	key := a.key(addr)
+	if key == nil {
+		return data, nil
+	}
+
+	packet, err := a.verifyKey(data, key)
+	if err != nil && a.Rejected != nil {
+		a.Rejected(addr, err)
+	}
+	return packet, err
+}
+
+// verifyKey verifies the signed packet data with key.
+func (a *Authenticator) verifyKey(data []byte, key []byte) ([]byte, error) {
+	// "#bundle", timetag, size of the signature message, size of the packet
+	if len(data) < 24 || !bytes.Equal(data[:8], append([]byte(bundleTagString), 0)) {
+		return nil, fmt.Errorf("%w: packet isn't signed", ErrorAuthentication)
+	}
+	timetag := data[8:16]
+
+	// compared as uint64, int may have 32 bits
+	size64 := uint64(binary.BigEndian.Uint32(data[16:20]))
+	if size64 > uint64(len(data)-24) {
+		return nil, fmt.Errorf("%w: packet isn't signed", ErrorAuthentication)
+	}
+	size := int(size64)
+	auth, err := UnmarshalPacket(data[20 : 20+size])
+	msg, ok := auth.(*Message)
+	if err != nil || !ok || msg.Address != authAddress || msg.TypeTags() != ",bb" {
+		return nil, fmt.Errorf("%w: packet isn't signed", ErrorAuthentication)
+	}
+	nonce := msg.Arguments[0].([]byte)
+	signature := msg.Arguments[1].([]byte)
+	if len(nonce) != authNonceSize {
+		return nil, fmt.Errorf("%w: packet isn't signed", ErrorAuthentication)
+	}
+
+	rest := data[20+size:]
+	if uint64(binary.BigEndian.Uint32(rest[:4])) != uint64(len(rest)-4) {
+		return nil, fmt.Errorf("%w: invalid packet size", ErrorAuthentication)
+	}
+	packet := rest[4:]
+
+	if !hmac.Equal(signature, authSignature(key, timetag, nonce, packet)) {
+		return nil, fmt.Errorf("%w: invalid signature", ErrorAuthentication)
+	}
+
+	now := time.Now()
+	sent := Timetag(binary.BigEndian.Uint64(timetag)).Time()
+	if age := now.Sub(sent); age > a.maxAge() || age < -a.maxAge() {
+		return nil, fmt.Errorf("%w: packet expired (%v)", ErrorAuthentication, age.Round(time.Millisecond))
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if a.nonces == nil {
+		a.nonces = make(map[string]time.Time)
+	}
+	if now.Sub(a.lastPurge) > a.maxAge() {
+		for n, expires := range a.nonces {
+			if now.After(expires) {
+				delete(a.nonces, n)
+			}
+		}
+		a.lastPurge = now
+	}
+	if _, ok := a.nonces[string(nonce)]; ok {
+		return nil, fmt.Errorf("%w: replayed packet", ErrorAuthentication)
+	}
+	// a replay is rejected as expired after this time
+	a.nonces[string(nonce)] = sent.Add(a.maxAge())
+
+	return packet, nil
+}
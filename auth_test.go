@@ -0,0 +1,254 @@
+package osc_test
+
+import (
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"bekuba.de/go-osc"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newAuthNode returns a serving node with an Authenticator, that sends all
+// received messages and rejections to the returned channels.
+func newAuthNode(t *testing.T, key []byte) (*osc.Node, chan *osc.Message, chan error) {
+	rejected := make(chan error, 10)
+	d, received := newMessageReceiver(t)
+	node := newServingNode(t, d, func(node *osc.Node) {
+		node.Auth = osc.NewAuthenticator(key)
+		node.Auth.Rejected = func(addr net.Addr, err error) {
+			rejected <- err
+		}
+	})
+	return node, received, rejected
+}
+
+// expectMessage waits for a message or a rejection.
+func expectMessage(t *testing.T, received chan *osc.Message, rejected chan error) (*osc.Message, error) {
+	t.Helper()
+	select {
+	case msg := <-received:
+		return msg, nil
+	case err := <-rejected:
+		return nil, err
+	case <-time.After(time.Second):
+		t.Fatal("timeout")
+		return nil, nil
+	}
+}
+
+func TestAuth(t *testing.T) {
+	key := []byte("secret")
+	server, received, rejected := newAuthNode(t, key)
+	addr := server.Conn().LocalAddr().String()
+
+	// signed
+	client, err := osc.NewNode("127.0.0.1:0")
+	assert.NoError(t, err)
+	defer client.Close()
+	client.Auth = osc.NewAuthenticator(key)
+
+	err = client.SendMsgTo(addr, "/cue/go", int32(1))
+	assert.NoError(t, err)
+	msg, err := expectMessage(t, received, rejected)
+	assert.NoError(t, err)
+	assert.Equal(t, osc.NewMessage("/cue/go", int32(1)), msg)
+
+	// bundles are signed too
+	bundle := osc.NewBundle(time.Now())
+	assert.NoError(t, bundle.Append(osc.NewMessage("/cue/go", int32(2))))
+	err = client.SendTo(addr, bundle)
+	assert.NoError(t, err)
+	msg, err = expectMessage(t, received, rejected)
+	assert.NoError(t, err)
+	assert.Equal(t, osc.NewMessage("/cue/go", int32(2)), msg)
+
+	// unsigned
+	anonymous, err := osc.NewNode("127.0.0.1:0")
+	assert.NoError(t, err)
+	defer anonymous.Close()
+
+	err = anonymous.SendMsgTo(addr, "/cue/go", int32(3))
+	assert.NoError(t, err)
+	_, err = expectMessage(t, received, rejected)
+	assert.ErrorIs(t, err, osc.ErrorAuthentication)
+
+	// wrong key
+	anonymous.Auth = osc.NewAuthenticator([]byte("guess"))
+	err = anonymous.SendMsgTo(addr, "/cue/go", int32(4))
+	assert.NoError(t, err)
+	_, err = expectMessage(t, received, rejected)
+	assert.ErrorIs(t, err, osc.ErrorAuthentication)
+	assert.Contains(t, err.Error(), "invalid signature")
+}
+
+func TestAuthReplay(t *testing.T) {
+	key := []byte("secret")
+	server, received, rejected := newAuthNode(t, key)
+	server.Auth.MaxAge = 200 * time.Millisecond
+	addr := server.Conn().LocalAddr()
+
+	// capture a signed packet
+	capture, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	assert.NoError(t, err)
+	defer capture.Close()
+
+	client, err := osc.NewNode("127.0.0.1:0")
+	assert.NoError(t, err)
+	defer client.Close()
+	client.Auth = osc.NewAuthenticator(key)
+	err = client.SendMsgTo(capture.LocalAddr().String(), "/cue/go")
+	assert.NoError(t, err)
+
+	data := make([]byte, 1024)
+	assert.NoError(t, capture.SetReadDeadline(time.Now().Add(time.Second)))
+	n, _, err := capture.ReadFrom(data)
+	assert.NoError(t, err)
+	data = data[:n]
+
+	// a receiver without key sees a bundle with the signature message
+	p, err := osc.UnmarshalPacket(data)
+	assert.NoError(t, err)
+	assert.IsType(t, &osc.Bundle{}, p)
+	assert.Len(t, p.(*osc.Bundle).Messages, 2)
+
+	// first delivery
+	_, err = capture.WriteTo(data, addr)
+	assert.NoError(t, err)
+	msg, err := expectMessage(t, received, rejected)
+	assert.NoError(t, err)
+	assert.Equal(t, osc.NewMessage("/cue/go"), msg)
+
+	// replay
+	_, err = capture.WriteTo(data, addr)
+	assert.NoError(t, err)
+	_, err = expectMessage(t, received, rejected)
+	assert.ErrorIs(t, err, osc.ErrorAuthentication)
+	assert.Contains(t, err.Error(), "replayed")
+
+	// expired
+	time.Sleep(250 * time.Millisecond)
+	_, err = capture.WriteTo(data, addr)
+	assert.NoError(t, err)
+	_, err = expectMessage(t, received, rejected)
+	assert.ErrorIs(t, err, osc.ErrorAuthentication)
+	assert.Contains(t, err.Error(), "expired")
+
+	// tampered
+	tampered := append([]byte(nil), data...)
+	tampered[len(tampered)-1] ^= 1
+	_, err = capture.WriteTo(tampered, addr)
+	assert.NoError(t, err)
+	_, err = expectMessage(t, received, rejected)
+	assert.ErrorIs(t, err, osc.ErrorAuthentication)
+}
+
+func TestAuthPeerKeys(t *testing.T) {
+	server, received, rejected := newAuthNode(t, nil)
+	addr := server.Conn().LocalAddr().String()
+
+	console, err := osc.NewNode("127.0.0.1:0")
+	assert.NoError(t, err)
+	defer console.Close()
+	console.Auth = osc.NewAuthenticator([]byte("console key"))
+
+	tablet, err := osc.NewNode("127.0.0.1:0")
+	assert.NoError(t, err)
+	defer tablet.Close()
+
+	// only the console needs a key
+	server.Auth.SetPeerKey(console.Conn().LocalAddr().String(), []byte("console key"))
+
+	err = console.SendMsgTo(addr, "/cue/go")
+	assert.NoError(t, err)
+	msg, err := expectMessage(t, received, rejected)
+	assert.NoError(t, err)
+	assert.Equal(t, "/cue/go", msg.Address)
+
+	err = tablet.SendMsgTo(addr, "/fader", float32(0.5))
+	assert.NoError(t, err)
+	msg, err = expectMessage(t, received, rejected)
+	assert.NoError(t, err)
+	assert.Equal(t, "/fader", msg.Address)
+
+	// all ports of the tablet's IP address
+	server.Auth.SetPeerKey("127.0.0.1", []byte("tablet key"))
+	err = tablet.SendMsgTo(addr, "/fader", float32(0.5))
+	assert.NoError(t, err)
+	_, err = expectMessage(t, received, rejected)
+	assert.ErrorIs(t, err, osc.ErrorAuthentication)
+
+	// the console key is more specific
+	err = console.SendMsgTo(addr, "/cue/go")
+	assert.NoError(t, err)
+	_, err = expectMessage(t, received, rejected)
+	assert.NoError(t, err)
+
+	// replies to the console are signed with its key
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		console.ReadTimeout = time.Second
+		p, _, err := console.Read()
+		assert.NoError(t, err)
+		assert.Equal(t, osc.NewMessage("/cue/done"), p)
+	}()
+	err = server.SendMsgTo(console.Conn().LocalAddr().String(), "/cue/done")
+	assert.NoError(t, err)
+	wg.Wait()
+
+	server.Auth.SetPeerKey("127.0.0.1", nil)
+	err = tablet.SendMsgTo(addr, "/fader", float32(0.5))
+	assert.NoError(t, err)
+	_, err = expectMessage(t, received, rejected)
+	assert.NoError(t, err)
+}
+
+func TestAuthOverhead(t *testing.T) {
+	key := []byte("secret")
+	server, received, rejected := newAuthNode(t, key)
+	addr := server.Conn().LocalAddr().String()
+
+	client, err := osc.NewNode("127.0.0.1:0")
+	assert.NoError(t, err)
+	defer client.Close()
+	client.MaxPacketSize = 256
+	client.Auth = osc.NewAuthenticator(key)
+
+	// address, type tags and blob size take 16 bytes
+	size := 256 - client.Auth.Overhead() - 16
+	err = client.SendTo(addr, osc.NewMessage("/blob", make([]byte, size+4)))
+	assert.ErrorIs(t, err, osc.ErrorPacketTooLarge)
+	err = client.SendTo(addr, osc.NewMessage("/blob", make([]byte, size)))
+	assert.NoError(t, err)
+
+	msg, err := expectMessage(t, received, rejected)
+	assert.NoError(t, err)
+	assert.Equal(t, osc.NewMessage("/blob", make([]byte, size)), msg)
+}
+
+func TestAuthMalformed(t *testing.T) {
+	server, received, rejected := newAuthNode(t, []byte("secret"))
+	addr := server.Conn().LocalAddr()
+
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	header := append([]byte("#bundle\x00"), 0, 0, 0, 0, 0, 0, 0, 1)
+	for _, data := range [][]byte{
+		header,
+		append(header, 0, 0, 0, 0),
+		// sizes, that are negative as int32
+		append(header, 0xff, 0xff, 0xff, 0xfc, 0, 0, 0, 0),
+		append(header, 0x80, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0),
+	} {
+		_, err = conn.WriteTo(data, addr)
+		assert.NoError(t, err)
+		_, err = expectMessage(t, received, rejected)
+		assert.ErrorIs(t, err, osc.ErrorAuthentication)
+	}
+}
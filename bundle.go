@@ -3,6 +3,8 @@ package osc
 import (
 	"bytes"
 	"encoding/binary"
+	"fmt"
+	"strings"
 	"time"
 )
 
@@ -35,6 +37,27 @@ func (b *Bundle) Append(pck Packet) error {
 	return nil
 }
 
+// String implements the fmt.Stringer interface. The format can be parsed by
+// ParseBundle.
+func (b *Bundle) String() string {
+	if b == nil {
+		return ""
+	}
+
+	elements := make([]string, 0, len(b.Messages)+len(b.Bundles))
+	for _, m := range b.Messages {
+		elements = append(elements, m.String())
+	}
+	for _, bundle := range b.Bundles {
+		elements = append(elements, bundle.String())
+	}
+
+	if len(elements) == 0 {
+		return fmt.Sprintf("#bundle %d { }", b.Timetag)
+	}
+	return fmt.Sprintf("#bundle %d { %s }", b.Timetag, strings.Join(elements, "; "))
+}
+
 // MarshalBinary serializes the OSC bundle to a byte array with the following
 // format:
 // 1. Bundle string: '#bundle'
@@ -106,6 +129,75 @@ func (b *Bundle) MarshalBinary() ([]byte, error) {
 	return data.Bytes(), nil
 }
 
+// Split splits the OSC bundle into several bundles with the same timetag.
+// Every returned bundle marshals to at most maxSize bytes. The order of the
+// bundle elements is preserved. Nested bundles, that are too large, are split
+// as well. An error is returned if a single message doesn't fit into a
+// bundle of maxSize bytes.
+func (b *Bundle) Split(maxSize int) ([]*Bundle, error) {
+	// '#bundle' string and timetag
+	const headerSize = 16
+
+	bundles := []*Bundle{}
+	current := &Bundle{Timetag: b.Timetag, Messages: []*Message{}, Bundles: []*Bundle{}}
+	size := headerSize
+
+	// add appends a bundle element of the given size, a new bundle is
+	// started if the element doesn't fit into the current one
+	add := func(pck Packet, elementSize int) error {
+		if size+elementSize > maxSize && size > headerSize {
+			bundles = append(bundles, current)
+			current = &Bundle{Timetag: b.Timetag, Messages: []*Message{}, Bundles: []*Bundle{}}
+			size = headerSize
+		}
+		if size+elementSize > maxSize {
+			return fmt.Errorf("%w: bundle element of %d bytes (max. %d bytes)", ErrorPacketTooLarge, elementSize, maxSize-headerSize)
+		}
+		size += elementSize
+		return current.Append(pck)
+	}
+
+	for _, m := range b.Messages {
+		buf, err := m.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		if err := add(m, 4+len(buf)); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, nested := range b.Bundles {
+		buf, err := nested.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		if headerSize+4+len(buf) <= maxSize {
+			if err := add(nested, 4+len(buf)); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		// the nested bundle is too large for an empty bundle, split it
+		parts, err := nested.Split(maxSize - headerSize - 4)
+		if err != nil {
+			return nil, err
+		}
+		for _, part := range parts {
+			buf, err := part.MarshalBinary()
+			if err != nil {
+				return nil, err
+			}
+			if err := add(part, 4+len(buf)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return append(bundles, current), nil
+}
+
 // NewBundle returns an OSC Bundle. Use this function to create a new OSC
 // Bundle.
 func NewBundle(time time.Time) *Bundle {
@@ -67,3 +67,59 @@ func TestBundle(t *testing.T) {
 	})
 
 }
+
+func TestBundleSplit(t *testing.T) {
+	bundle := osc.NewBundle(time.Now())
+	for i := 0; i < 100; i++ {
+		err := bundle.Append(osc.NewMessage("/meter", int32(i), "some text to fill the bundle"))
+		assert.Nil(t, err)
+	}
+	nested := osc.NewBundle(time.Now())
+	for i := 0; i < 50; i++ {
+		err := nested.Append(osc.NewMessage("/nested", int32(i), "some text to fill the bundle"))
+		assert.Nil(t, err)
+	}
+	err := bundle.Append(nested)
+	assert.Nil(t, err)
+
+	t.Run("should split bundle into bundles of max size", func(t *testing.T) {
+		bundles, err := bundle.Split(osc.EthernetMaxPacketSize)
+		assert.Nil(t, err)
+		assert.Greater(t, len(bundles), 1)
+
+		var messages []*osc.Message
+		var nestedMessages []*osc.Message
+		for _, b := range bundles {
+			d, err := b.MarshalBinary()
+			assert.Nil(t, err)
+			assert.LessOrEqual(t, len(d), osc.EthernetMaxPacketSize)
+			assert.Equal(t, bundle.Timetag, b.Timetag)
+
+			messages = append(messages, b.Messages...)
+			for _, n := range b.Bundles {
+				assert.Equal(t, nested.Timetag, n.Timetag)
+				nestedMessages = append(nestedMessages, n.Messages...)
+			}
+		}
+
+		// order of the elements is preserved
+		assert.Equal(t, bundle.Messages, messages)
+		assert.Equal(t, nested.Messages, nestedMessages)
+	})
+
+	t.Run("should return one bundle if bundle is small enough", func(t *testing.T) {
+		bundles, err := bundle.Split(osc.DefaultMaxPacketSize)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(bundles))
+		assert.Equal(t, bundle, bundles[0])
+	})
+
+	t.Run("should fail if a message is too large", func(t *testing.T) {
+		b := osc.NewBundle(time.Now())
+		err := b.Append(osc.NewMessage("/blob", make([]byte, 2000)))
+		assert.Nil(t, err)
+
+		_, err = b.Split(osc.EthernetMaxPacketSize)
+		assert.ErrorIs(t, err, osc.ErrorPacketTooLarge)
+	})
+}
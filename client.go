@@ -0,0 +1,105 @@
+package osc
+
+import (
+	"net"
+	"sync"
+	"time"
+)
+
+// Client is an OSC Node bound to one remote peer, e.g. the device an
+// application is talking to. The resolved address of the peer is cached.
+type Client struct {
+	*Node
+
+	// ResolveInterval is the interval after which the remote address is
+	// resolved again, e.g. for DNS names of devices with changing IP
+	// addresses. Zero means the address is resolved only once.
+	ResolveInterval time.Duration
+
+	// PeerOnly drops all incoming packets, that are not sent by the remote
+	// peer, before they are read, dispatched or taken as replies of Request.
+	PeerOnly bool
+
+	raddr    string
+	mutex    sync.Mutex
+	addr     *net.UDPAddr
+	resolved time.Time
+}
+
+// Dial returns a Client for the remote address raddr. The client listens on
+// a random local port.
+func Dial(raddr string) (*Client, error) {
+	return DialFrom(":0", raddr)
+}
+
+// DialFrom returns a Client for the remote address raddr, that listens on the
+// local address laddr.
+func DialFrom(laddr, raddr string) (*Client, error) {
+	node, err := NewNode(laddr)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Client{Node: node, raddr: raddr}
+	node.accept = func(addr net.Addr) bool {
+		return !c.PeerOnly || c.IsPeer(addr)
+	}
+	if _, err := c.RemoteAddr(); err != nil {
+		node.Close()
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// RemoteAddr returns the resolved address of the remote peer. The address is
+// resolved again, if ResolveInterval is elapsed. If resolving fails, the last
+// resolved address is kept.
+func (c *Client) RemoteAddr() (*net.UDPAddr, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.addr != nil && (c.ResolveInterval <= 0 || time.Since(c.resolved) < c.ResolveInterval) {
+		return c.addr, nil
+	}
+
+	addr, err := net.ResolveUDPAddr(NetworkUDP, c.raddr)
+	if err != nil {
+		if c.addr != nil {
+			return c.addr, nil
+		}
+		return nil, ErrorOscAddressFormat
+	}
+
+	c.addr = addr
+	c.resolved = time.Now()
+	return addr, nil
+}
+
+// Send sends an OSC Bundle or an OSC Message to the remote peer.
+func (c *Client) Send(packet Packet) error {
+	addr, err := c.RemoteAddr()
+	if err != nil {
+		return err
+	}
+	return c.SendToUDPAddr(addr, packet)
+}
+
+// SendMsg sends an OSC Message to the remote peer (all int types converted to
+// int32, see SendMsgTo).
+func (c *Client) SendMsg(path string, args ...any) error {
+	addr, err := c.RemoteAddr()
+	if err != nil {
+		return err
+	}
+	return c.SendMsgToUDPAddr(addr, path, args...)
+}
+
+// IsPeer returns true if addr is the address of the remote peer.
+func (c *Client) IsPeer(addr net.Addr) bool {
+	peer, err := c.RemoteAddr()
+	if err != nil {
+		return false
+	}
+	return sameAddr(peer, addr)
+}
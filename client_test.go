@@ -0,0 +1,150 @@
+package osc_test
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"bekuba.de/go-osc"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDial(t *testing.T) {
+	server, err := osc.NewNode("127.0.0.1:0")
+	assert.NoError(t, err)
+	defer server.Close()
+	addr := server.Conn().LocalAddr().String()
+
+	received := make(chan *osc.Message, 10)
+	d := osc.NewStandardDispatcher()
+	err = d.AddMsgHandlerExt(ping, func(msg *osc.Message, raddr net.Addr) {
+		received <- msg
+		err := server.SendMsgTo(raddr.String(), pong, msg.Arguments...)
+		assert.NoError(t, err)
+	})
+	assert.NoError(t, err)
+	go server.ListenAndServe(d)
+
+	client, err := osc.Dial(addr)
+	assert.NoError(t, err)
+	defer client.Close()
+	client.PeerOnly = true
+
+	raddr, err := client.RemoteAddr()
+	assert.NoError(t, err)
+	assert.Equal(t, addr, raddr.String())
+
+	pongs := make(chan *osc.Message, 10)
+	dc := osc.NewStandardDispatcher()
+	err = dc.AddMsgHandler(pong, func(msg *osc.Message) {
+		pongs <- msg
+	})
+	assert.NoError(t, err)
+	go client.ListenAndServe(dc)
+
+	// Send and SendMsg
+	err = client.Send(osc.NewMessage(ping, int32(1)))
+	assert.NoError(t, err)
+	err = client.SendMsg(ping, 2)
+	assert.NoError(t, err)
+
+	for i := int32(1); i <= 2; i++ {
+		select {
+		case msg := <-pongs:
+			assert.Equal(t, osc.NewMessage(pong, i), msg)
+		case <-time.After(time.Second):
+			t.Fatal("no reply from peer")
+		}
+	}
+
+	// packets of other peers are dropped
+	other, err := osc.NewNode("127.0.0.1:0")
+	assert.NoError(t, err)
+	defer other.Close()
+
+	clientAddr := fmt.Sprintf("127.0.0.1:%d", client.Conn().LocalAddr().(*net.UDPAddr).Port)
+	err = other.SendMsgTo(clientAddr, pong, 3)
+	assert.NoError(t, err)
+	err = server.SendMsgTo(clientAddr, pong, 4)
+	assert.NoError(t, err)
+
+	select {
+	case msg := <-pongs:
+		assert.Equal(t, osc.NewMessage(pong, int32(4)), msg)
+	case <-time.After(time.Second):
+		t.Fatal("no message from peer")
+	}
+	select {
+	case msg := <-pongs:
+		t.Errorf("unexpected message %v", msg)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestDialPeerOnly(t *testing.T) {
+	peer, _ := newReceiver(t)
+	peerAddr := peer.Conn().LocalAddr().String()
+	echoAddr := newEchoServer(t, 0)
+
+	client, err := osc.Dial(peerAddr)
+	assert.NoError(t, err)
+	defer client.Close()
+	clientAddr := fmt.Sprintf("127.0.0.1:%d", client.Conn().LocalAddr().(*net.UDPAddr).Port)
+
+	// Read drops packets of other sources
+	client.PeerOnly = true
+	client.ReadTimeout = time.Second
+	other, err := osc.NewNode("127.0.0.1:0")
+	assert.NoError(t, err)
+	defer other.Close()
+	assert.NoError(t, other.SendMsgTo(clientAddr, pong, 1))
+	assert.NoError(t, peer.SendMsgTo(clientAddr, pong, 2))
+	p, addr, err := client.Read()
+	assert.NoError(t, err)
+	assert.Equal(t, osc.NewMessage(pong, int32(2)), p)
+	assert.Equal(t, peerAddr, addr.String())
+	client.ReadTimeout = 0
+
+	go client.ListenAndServe(nil)
+
+	// a reply of another source doesn't complete a request
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err = client.Request(ctx, echoAddr, osc.NewMessage("/status"), nil)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// the same request of a client without PeerOnly is completed
+	client2, err := osc.Dial(peerAddr)
+	assert.NoError(t, err)
+	defer client2.Close()
+	go client2.ListenAndServe(nil)
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	reply, err := client2.Request(ctx, echoAddr, osc.NewMessage("/status"), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, osc.NewMessage("/status"), reply)
+}
+
+func TestDialResolve(t *testing.T) {
+	_, err := osc.Dial("no-port")
+	assert.ErrorIs(t, err, osc.ErrorOscAddressFormat)
+
+	client, err := osc.Dial("localhost:9000")
+	assert.NoError(t, err)
+	defer client.Close()
+	client.ResolveInterval = time.Millisecond
+
+	addr1, err := client.RemoteAddr()
+	assert.NoError(t, err)
+	time.Sleep(2 * time.Millisecond)
+	addr2, err := client.RemoteAddr()
+	assert.NoError(t, err)
+
+	// resolved again
+	assert.NotSame(t, addr1, addr2)
+	assert.Equal(t, 9000, addr2.Port)
+	assert.True(t, client.IsPeer(addr2))
+}
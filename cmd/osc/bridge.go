@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"bekuba.de/go-osc"
+)
+
+// bridge forwards the packets of UDP, TCP and WebSocket clients to a UDP host
+// and the packets of the host to all clients.
+func bridge(ctx context.Context, args []string) error {
+	fs := newFlagSet("bridge", "host:port")
+	bind := fs.String("bind", ":0", "local address of the connection to the host")
+	udp := fs.String("udp", "", "listen for UDP clients on this address")
+	expire := fs.Duration("expire", time.Minute, "forget UDP clients after this time without packets")
+	tcp := fs.String("tcp", "", "listen for TCP clients on this address")
+	framing := fs.String("framing", "length", "framing of TCP packets: length (OSC 1.0) or slip (OSC 1.1)")
+	ws := fs.String("ws", "", "listen for WebSocket clients on this address")
+	_ = fs.Parse(args)
+
+	if fs.NArg() != 1 || (*udp == "" && *tcp == "" && *ws == "") {
+		fs.Usage()
+		return errors.New("missing host or client address")
+	}
+	raddr := fs.Arg(0)
+	host, err := net.ResolveUDPAddr(osc.NetworkUDP, raddr)
+	if err != nil {
+		return fmt.Errorf("%s: %w", raddr, err)
+	}
+
+	node, err := newNode(osc.NetworkUDP, *bind)
+	if err != nil {
+		return err
+	}
+	// closed by serve at last
+	closeNode := true
+	defer func() {
+		if closeNode {
+			node.Close()
+		}
+	}()
+
+	// forwards the packets of all clients to the host
+	toHost := dispatcherFunc(func(packet osc.Packet, addr net.Addr) error {
+		_ = node.SendToAddr(host, packet)
+		return nil
+	})
+	// forwards the packets of the host to all clients
+	var fromHost []func(packet osc.Packet)
+
+	if *udp != "" {
+		udpNode, err := newNode(osc.NetworkUDP, *udp)
+		if err != nil {
+			return err
+		}
+		defer udpNode.Close()
+
+		// the clients are the peers, that sent packets recently
+		udpNode.Peers = osc.NewPeerRegistry(*expire)
+		go udpNode.ListenAndServe(toHost)
+		fromHost = append(fromHost, func(packet osc.Packet) {
+			for _, addr := range udpNode.Peers.Addrs() {
+				_ = udpNode.SendToAddr(addr, packet)
+			}
+		})
+	}
+
+	if *tcp != "" {
+		f := osc.FramingLengthPrefix
+		switch *framing {
+		case "length":
+		case "slip":
+			f = osc.FramingSLIP
+		default:
+			return fmt.Errorf("unknown framing %q", *framing)
+		}
+
+		stream := osc.NewStreamServer(toHost, f)
+		defer stream.Close()
+		l, err := net.Listen("tcp", *tcp)
+		if err != nil {
+			return err
+		}
+		go stream.Serve(l)
+		fromHost = append(fromHost, func(packet osc.Packet) {
+			_ = stream.SendToAll(packet)
+		})
+	}
+
+	if *ws != "" {
+		wsServer := osc.NewWebSocketServer(nil)
+		defer wsServer.Close()
+		stop, err := wsServer.Bridge(node, raddr)
+		if err != nil {
+			return err
+		}
+		defer stop()
+
+		l, err := net.Listen("tcp", *ws)
+		if err != nil {
+			return err
+		}
+		server := &http.Server{Handler: wsServer}
+		defer server.Close()
+		go server.Serve(l)
+	}
+
+	closeNode = false
+	return serve(ctx, node, dispatcherFunc(func(packet osc.Packet, addr net.Addr) error {
+		if addr.String() != host.String() {
+			return nil
+		}
+		for _, f := range fromHost {
+			f(packet)
+		}
+		return nil
+	}))
+}
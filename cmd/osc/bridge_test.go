@@ -0,0 +1,60 @@
+package main
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"bekuba.de/go-osc"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBridgeUDP(t *testing.T) {
+	// the host answers every packet and remembers the bridge address
+	host, err := osc.NewNode("127.0.0.1:0")
+	assert.NoError(t, err)
+	defer host.Close()
+	bridgeAddrs := make(chan net.Addr, 100)
+	go host.ListenAndServe(dispatcherFunc(func(packet osc.Packet, addr net.Addr) error {
+		bridgeAddrs <- addr
+		return host.SendToAddr(addr, osc.NewMessage("/reply"))
+	}))
+
+	udp := freeUDPAddr(t)
+	start(t, bridge, "-udp", udp, "-expire", "100ms", host.Conn().LocalAddr().String())
+
+	client, err := osc.NewNode("127.0.0.1:0")
+	assert.NoError(t, err)
+	defer client.Close()
+	received := make(chan osc.Packet, 100)
+	go client.ListenAndServe(dispatcherFunc(func(packet osc.Packet, addr net.Addr) error {
+		received <- packet
+		return nil
+	}))
+
+	// sent until the bridge listens
+	assert.Eventually(t, func() bool {
+		assert.NoError(t, client.SendMsgTo(udp, "/ping"))
+		select {
+		case packet := <-received:
+			assert.Equal(t, osc.NewMessage("/reply"), packet)
+			return true
+		case <-time.After(20 * time.Millisecond):
+			return false
+		}
+	}, 2*time.Second, time.Millisecond)
+
+	// the client is forgotten after the expiry
+	bridgeAddr := <-bridgeAddrs
+	time.Sleep(300 * time.Millisecond)
+	for len(received) > 0 {
+		<-received
+	}
+	assert.NoError(t, host.SendToAddr(bridgeAddr, osc.NewMessage("/late")))
+	select {
+	case packet := <-received:
+		t.Fatalf("unexpected packet %v", packet)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
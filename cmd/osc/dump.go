@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"time"
+
+	"bekuba.de/go-osc"
+)
+
+// dump prints received messages.
+func dump(ctx context.Context, args []string) error {
+	fs := newFlagSet("dump", "laddr")
+	network := fs.String("network", osc.NetworkUDP, "network: udp, udp4, udp6, dual or unixgram")
+	address := fs.String("address", "", "print the messages to this OSC address pattern only (e.g. /ch/*/mix/fader)")
+	source := fs.String("source", "", "print the messages of this network (CIDR notation) or IP address only")
+	pcap := fs.String("pcap", "", "print the messages of a pcap or pcapng capture instead of listening on laddr")
+	port := fs.Int("port", 0, "print the UDP datagrams of a capture from or to this port only")
+	_ = fs.Parse(args)
+
+	if *pcap == "" && fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("missing local address")
+	}
+
+	// the time of the dispatched packet
+	now := time.Now
+	d := osc.NewStandardDispatcher()
+	err := d.AddMsgHandlerExt("*", func(msg *osc.Message, addr net.Addr) {
+		printMessage(now(), msg, addr)
+	})
+	if err != nil {
+		return err
+	}
+
+	var dispatcher osc.Dispatcher = d
+	if *address != "" || *source != "" {
+		acl := osc.NewACL(d, osc.ACLDeny)
+		if err := acl.Allow(*source, *address); err != nil {
+			return err
+		}
+		dispatcher = acl
+	}
+
+	if *pcap != "" {
+		f, err := os.Open(*pcap)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		r, err := osc.NewPcapReader(f)
+		if err != nil {
+			return err
+		}
+		r.Port = *port
+
+		for ctx.Err() == nil {
+			p, err := r.Next()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			now = func() time.Time { return p.Time }
+			if err := dispatcher.Dispatch(p.Packet, p.Src); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	node, err := newNode(*network, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	return serve(ctx, node, dispatcher)
+}
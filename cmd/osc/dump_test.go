@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"bekuba.de/go-osc"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDump(t *testing.T) {
+	out := captureOutput(t)
+	addr := freeUDPAddr(t)
+	start(t, dump, "-address", "/cue/*", addr)
+
+	client, err := osc.NewNode("127.0.0.1:0")
+	assert.NoError(t, err)
+	defer client.Close()
+
+	// sent until dump listens
+	assert.Eventually(t, func() bool {
+		assert.NoError(t, client.SendMsgTo(addr, "/fader/1", float32(0.5)))
+		assert.NoError(t, client.SendMsgTo(addr, "/cue/go", int32(1)))
+		return strings.Contains(out.String(), "/cue/go ,i 1")
+	}, time.Second, 10*time.Millisecond)
+
+	assert.Contains(t, out.String(), " "+client.Conn().LocalAddr().String()+" /cue/go ,i 1\n")
+	assert.NotContains(t, out.String(), "/fader/1")
+}
+
+func TestDumpPcap(t *testing.T) {
+	out := captureOutput(t)
+
+	name := filepath.Join(t.TempDir(), "capture.pcap")
+	f, err := os.Create(name)
+	assert.NoError(t, err)
+	w, err := osc.NewPcapWriter(f)
+	assert.NoError(t, err)
+
+	console := &net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 9000}
+	rig := &net.UDPAddr{IP: net.IPv4(10, 0, 0, 2), Port: 8000}
+	other := &net.UDPAddr{IP: net.IPv4(10, 0, 0, 2), Port: 7000}
+	captured := time.Date(2024, 5, 1, 12, 0, 0, 0, time.Local)
+	assert.NoError(t, w.WritePacket(captured, console, rig, osc.NewMessage("/cue/go", int32(1))))
+	assert.NoError(t, w.WritePacket(captured, console, other, osc.NewMessage("/other")))
+	assert.NoError(t, f.Close())
+
+	assert.NoError(t, dump(context.Background(), []string{"-pcap", name, "-port", "8000"}))
+	assert.Equal(t, "12:00:00.000000 10.0.0.1:9000 /cue/go ,i 1\n", out.String())
+}
@@ -0,0 +1,127 @@
+// Command osc sends, dumps, records, replays and bridges OSC packets.
+//
+// Usage:
+//
+//	osc send [flags] host:port /address [arguments...]
+//	osc dump [flags] laddr
+//	osc dump [flags] -pcap file
+//	osc record [flags] -o file laddr
+//	osc replay [flags] file host:port
+//	osc bridge [flags] host:port
+//
+// Run "osc <command> -h" for the flags of a command.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+	"time"
+
+	"bekuba.de/go-osc"
+)
+
+// output is the output of printed messages.
+var output io.Writer = os.Stdout
+
+// commands are the subcommands by name.
+var commands = map[string]func(ctx context.Context, args []string) error{
+	"send":   send,
+	"dump":   dump,
+	"record": record,
+	"replay": replay,
+	"bridge": bridge,
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		usage()
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if err := cmd(ctx, os.Args[2:]); err != nil {
+		fmt.Fprintf(os.Stderr, "osc %s: %v\n", os.Args[1], err)
+		os.Exit(1)
+	}
+}
+
+// usage prints the commands.
+func usage() {
+	fmt.Fprint(os.Stderr, `Usage: osc <command> [flags] [arguments]
+
+Commands:
+  send    send an OSC message
+  dump    print received OSC messages
+  record  record received OSC packets to a file
+  replay  send the OSC packets of a recording
+  bridge  forward OSC packets between UDP, TCP and WebSocket clients and a UDP host
+
+Run "osc <command> -h" for the flags of a command.
+`)
+}
+
+// newFlagSet returns the flags of the command name with the usage line
+// arguments.
+func newFlagSet(name, arguments string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: osc %s [flags] %s\n\nFlags:\n", name, arguments)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// newNode returns a node of network bound to laddr.
+func newNode(network, laddr string) (*osc.Node, error) {
+	node, err := osc.NewNodeNetwork(network, laddr)
+	if err != nil {
+		return nil, fmt.Errorf("%s %s: %w", network, laddr, err)
+	}
+	return node, nil
+}
+
+// printMessage prints a message with the time and its source address.
+func printMessage(t time.Time, msg *osc.Message, addr net.Addr) {
+	source := "-"
+	if addr != nil {
+		source = addr.String()
+	}
+	fmt.Fprintf(output, "%s %s %v\n", t.Format("15:04:05.000000"), source, msg)
+}
+
+// dispatcherFunc is a Dispatcher function.
+type dispatcherFunc func(packet osc.Packet, addr net.Addr) error
+
+// Dispatch implements the osc.Dispatcher interface.
+func (f dispatcherFunc) Dispatch(packet osc.Packet, addr net.Addr) error {
+	return f(packet, addr)
+}
+
+// serve serves node with d until ctx is done, then node is closed.
+func serve(ctx context.Context, node *osc.Node, d osc.Dispatcher) error {
+	errs := make(chan error, 1)
+	go func() {
+		errs <- node.ListenAndServe(d)
+	}()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+		node.Close()
+		<-errs
+		return nil
+	}
+}
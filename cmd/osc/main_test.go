@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// syncBuffer is a bytes.Buffer safe for concurrent use.
+type syncBuffer struct {
+	mutex sync.Mutex
+	buf   bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buf.String()
+}
+
+// captureOutput redirects the printed messages to the returned buffer until
+// the end of the test.
+func captureOutput(t *testing.T) *syncBuffer {
+	buf := &syncBuffer{}
+	previous := output
+	output = buf
+	t.Cleanup(func() { output = previous })
+	return buf
+}
+
+// freeUDPAddr returns a free local UDP address.
+func freeUDPAddr(t *testing.T) string {
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer conn.Close()
+	return conn.LocalAddr().String()
+}
+
+// start runs the command cmd with args until the returned function is called
+// or the test ends.
+func start(t *testing.T, cmd func(ctx context.Context, args []string) error, args ...string) (stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	errs := make(chan error, 1)
+	go func() {
+		errs <- cmd(ctx, args)
+	}()
+
+	var once sync.Once
+	stop = func() {
+		once.Do(func() {
+			cancel()
+			select {
+			case err := <-errs:
+				assert.NoError(t, err)
+			case <-time.After(time.Second):
+				t.Error("command didn't stop")
+			}
+		})
+	}
+	t.Cleanup(stop)
+	return stop
+}
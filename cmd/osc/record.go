@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"time"
+
+	"bekuba.de/go-osc"
+)
+
+// record records received packets to a file.
+func record(ctx context.Context, args []string) error {
+	fs := newFlagSet("record", "-o file laddr")
+	network := fs.String("network", osc.NetworkUDP, "network: udp, udp4, udp6, dual or unixgram")
+	output := fs.String("o", "", "recording file")
+	quiet := fs.Bool("q", false, "don't print the recorded messages")
+	_ = fs.Parse(args)
+
+	if *output == "" || fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("missing recording file or local address")
+	}
+
+	node, err := newNode(*network, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(*output)
+	if err != nil {
+		node.Close()
+		return err
+	}
+	defer f.Close()
+
+	var next osc.Dispatcher
+	if !*quiet {
+		d := osc.NewStandardDispatcher()
+		err := d.AddMsgHandlerExt("*", func(msg *osc.Message, addr net.Addr) {
+			printMessage(time.Now(), msg, addr)
+		})
+		if err != nil {
+			node.Close()
+			return err
+		}
+		next = d
+	}
+
+	r, err := osc.NewRecorder(f, next)
+	if err != nil {
+		node.Close()
+		return err
+	}
+	if err := serve(ctx, node, r); err != nil {
+		return err
+	}
+	if err := r.Err(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// replay sends the packets of a recording.
+func replay(ctx context.Context, args []string) error {
+	fs := newFlagSet("replay", "file host:port")
+	network := fs.String("network", osc.NetworkUDP, "network: udp, udp4, udp6 or unixgram")
+	bind := fs.String("bind", ":0", "local address")
+	speed := fs.Float64("speed", 1, "speed factor of the timing, negative for as fast as possible")
+	seek := fs.Duration("seek", 0, "start at this offset of the recording")
+	loop := fs.Bool("loop", false, "restart at the beginning after the last packet")
+	_ = fs.Parse(args)
+
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return errors.New("missing recording file or remote address")
+	}
+
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	player, err := osc.NewPlayer(f)
+	f.Close()
+	if err != nil {
+		return err
+	}
+	player.Speed = *speed
+	player.Loop = *loop
+	player.Seek(*seek)
+
+	node, err := newNode(*network, *bind)
+	if err != nil {
+		return err
+	}
+	defer node.Close()
+
+	err = player.Send(ctx, node, fs.Arg(1))
+	if errors.Is(err, context.Canceled) {
+		return nil
+	}
+	return err
+}
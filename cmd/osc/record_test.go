@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"bekuba.de/go-osc"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordReplay(t *testing.T) {
+	out := captureOutput(t)
+	name := filepath.Join(t.TempDir(), "show.oscrec")
+	addr := freeUDPAddr(t)
+	stop := start(t, record, "-o", name, addr)
+
+	client, err := osc.NewNode("127.0.0.1:0")
+	assert.NoError(t, err)
+	defer client.Close()
+
+	// sent until record listens
+	assert.Eventually(t, func() bool {
+		assert.NoError(t, client.SendMsgTo(addr, "/cue/go", int32(1)))
+		return strings.Contains(out.String(), "/cue/go ,i 1")
+	}, time.Second, 10*time.Millisecond)
+	assert.NoError(t, client.SendMsgTo(addr, "/cue/stop", int32(2)))
+	assert.Eventually(t, func() bool {
+		return strings.Contains(out.String(), "/cue/stop ,i 2")
+	}, time.Second, time.Millisecond)
+	stop()
+
+	f, err := os.Open(name)
+	assert.NoError(t, err)
+	p, err := osc.NewPlayer(f)
+	f.Close()
+	assert.NoError(t, err)
+	assert.Equal(t, osc.NewMessage("/cue/stop", int32(2)), p.Records[len(p.Records)-1].Packet)
+
+	// the recording is replayed in order
+	receiver, err := osc.NewNode("127.0.0.1:0")
+	assert.NoError(t, err)
+	defer receiver.Close()
+	received := make(chan osc.Packet, 100)
+	go receiver.ListenAndServe(dispatcherFunc(func(packet osc.Packet, addr net.Addr) error {
+		received <- packet
+		return nil
+	}))
+
+	err = replay(context.Background(), []string{"-speed", "-1", name, receiver.Conn().LocalAddr().String()})
+	assert.NoError(t, err)
+	for _, rec := range p.Records {
+		select {
+		case packet := <-received:
+			assert.Equal(t, rec.Packet, packet)
+		case <-time.After(time.Second):
+			t.Fatal("timeout")
+		}
+	}
+}
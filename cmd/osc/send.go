@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"bekuba.de/go-osc"
+)
+
+// send sends a message.
+func send(ctx context.Context, args []string) error {
+	fs := newFlagSet("send", "host:port /address [arguments...]")
+	network := fs.String("network", osc.NetworkUDP, "network: udp, udp4, udp6 or unixgram")
+	bind := fs.String("bind", ":0", "local address")
+	types := fs.String("types", "", "type tags of the arguments (e.g. ifsb), inferred if empty")
+	reply := fs.Bool("reply", false, "wait for a reply with the same address and print it")
+	timeout := fs.Duration("timeout", time.Second, "time to wait for a reply")
+	_ = fs.Parse(args)
+
+	if fs.NArg() < 2 {
+		fs.Usage()
+		return errors.New("missing address")
+	}
+	raddr, address := fs.Arg(0), fs.Arg(1)
+	arguments, err := parseArguments(*types, fs.Args()[2:])
+	if err != nil {
+		return err
+	}
+	msg := osc.NewMessage(address, arguments...)
+
+	node, err := newNode(*network, *bind)
+	if err != nil {
+		return err
+	}
+	defer node.Close()
+
+	if !*reply {
+		return node.SendTo(raddr, msg)
+	}
+
+	// the reply is sent by raddr
+	source, err := resolveAddr(*network, raddr)
+	if err != nil {
+		return err
+	}
+	go node.ListenAndServe(nil)
+	ctx, cancel := context.WithTimeout(ctx, *timeout)
+	defer cancel()
+	answer, err := node.Request(ctx, raddr, msg, nil)
+	if err != nil {
+		return err
+	}
+	printMessage(time.Now(), answer, source)
+	return nil
+}
+
+// resolveAddr returns the address raddr of network.
+func resolveAddr(network, raddr string) (net.Addr, error) {
+	if network == osc.NetworkUnixgram {
+		return net.ResolveUnixAddr(network, raddr)
+	}
+	return net.ResolveUDPAddr(network, raddr)
+}
+
+// parseArguments returns the arguments of a message. Without type tags, the
+// types are inferred: true, false, nil, int32 (int64 if out of range),
+// float32 and string.
+func parseArguments(types string, args []string) ([]any, error) {
+	types = strings.TrimPrefix(types, ",")
+	if types == "" {
+		arguments := make([]any, len(args))
+		for i, arg := range args {
+			arguments[i] = inferArgument(arg)
+		}
+		return arguments, nil
+	}
+
+	var arguments []any
+	for _, tag := range types {
+		// arguments without value
+		switch tag {
+		case 'T':
+			arguments = append(arguments, true)
+			continue
+		case 'F':
+			arguments = append(arguments, false)
+			continue
+		case 'N':
+			arguments = append(arguments, nil)
+			continue
+		}
+
+		if len(args) == 0 {
+			return nil, fmt.Errorf("missing argument of type %c", tag)
+		}
+		arg := args[0]
+		args = args[1:]
+
+		v, err := parseArgument(tag, arg)
+		if err != nil {
+			return nil, fmt.Errorf("argument %q of type %c: %w", arg, tag, err)
+		}
+		arguments = append(arguments, v)
+	}
+	if len(args) > 0 {
+		return nil, fmt.Errorf("%d arguments without type tag", len(args))
+	}
+	return arguments, nil
+}
+
+// parseArgument returns the argument arg of the type tag.
+func parseArgument(tag rune, arg string) (any, error) {
+	switch tag {
+	case 'i':
+		v, err := strconv.ParseInt(arg, 0, 32)
+		return int32(v), err
+	case 'h':
+		v, err := strconv.ParseInt(arg, 0, 64)
+		return v, err
+	case 'f':
+		v, err := strconv.ParseFloat(arg, 32)
+		return float32(v), err
+	case 'd':
+		return strconv.ParseFloat(arg, 64)
+	case 's':
+		return arg, nil
+	case 'b':
+		return hex.DecodeString(strings.TrimPrefix(arg, "0x"))
+	case 't':
+		return parseTimetag(arg)
+	}
+	return nil, errors.New("unknown type tag")
+}
+
+// parseTimetag returns the timetag of "now", "immediate", an RFC 3339 time or
+// a number.
+func parseTimetag(arg string) (osc.Timetag, error) {
+	switch arg {
+	case "now":
+		return osc.NewTimetag(), nil
+	case "immediate":
+		return osc.NewImmediateTimetag(), nil
+	}
+	if t, err := time.Parse(time.RFC3339Nano, arg); err == nil {
+		return osc.NewTimetagFromTime(t), nil
+	}
+	v, err := strconv.ParseUint(arg, 0, 64)
+	return osc.Timetag(v), err
+}
+
+// inferArgument returns the argument arg of the inferred type.
+func inferArgument(arg string) any {
+	switch arg {
+	case "true":
+		return true
+	case "false":
+		return false
+	case "nil":
+		return nil
+	}
+	if v, err := strconv.ParseInt(arg, 10, 32); err == nil {
+		return int32(v)
+	}
+	if v, err := strconv.ParseInt(arg, 10, 64); err == nil {
+		return v
+	}
+	// not inf or nan
+	if strings.ContainsAny(arg, "0123456789") {
+		if v, err := strconv.ParseFloat(arg, 32); err == nil {
+			return float32(v)
+		}
+	}
+	return arg
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+
+	"bekuba.de/go-osc"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseArguments(t *testing.T) {
+	args, err := parseArguments("", []string{"1", "-2", "9999999999", "0.5", "1e3", "true", "false", "nil", "hello", "inf", ""})
+	assert.NoError(t, err)
+	assert.Equal(t, []any{int32(1), int32(-2), int64(9999999999), float32(0.5), float32(1000), true, false, nil, "hello", "inf", ""}, args)
+
+	args, err = parseArguments(",ihfdsbTFNt", []string{"0x10", "2", "0.5", "0.25", "1", "c0ffee", "immediate"})
+	assert.NoError(t, err)
+	assert.Equal(t, []any{int32(16), int64(2), float32(0.5), float64(0.25), "1", []byte{0xc0, 0xff, 0xee}, true, false, nil, osc.NewImmediateTimetag()}, args)
+
+	for _, tt := range []struct {
+		types string
+		args  []string
+	}{
+		{"i", []string{"one"}},
+		{"i", []string{"9999999999"}},
+		{"b", []string{"xyz"}},
+		{"ii", []string{"1"}},
+		{"i", []string{"1", "2"}},
+		{"c", []string{"a"}},
+	} {
+		_, err := parseArguments(tt.types, tt.args)
+		assert.Error(t, err, "%s %v", tt.types, tt.args)
+	}
+}
+
+func TestSendReply(t *testing.T) {
+	out := captureOutput(t)
+
+	// echo server
+	server, err := osc.NewNode("127.0.0.1:0")
+	assert.NoError(t, err)
+	defer server.Close()
+	addr := server.Conn().LocalAddr().String()
+	d := osc.NewStandardDispatcher()
+	err = d.AddMsgHandlerExt("*", func(msg *osc.Message, from net.Addr) {
+		assert.NoError(t, server.SendTo(from.String(), msg))
+	})
+	assert.NoError(t, err)
+	go server.ListenAndServe(d)
+
+	err = send(context.Background(), []string{"-reply", "-bind", "127.0.0.1:0", addr, "/xinfo", "1", "mixer"})
+	assert.NoError(t, err)
+	assert.True(t, strings.HasSuffix(out.String(), " "+addr+` /xinfo ,is 1 "mixer"`+"\n"), out.String())
+}
@@ -0,0 +1,196 @@
+package osc
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+// DefaultCoalesceInterval is the interval of a Coalescer, if no positive
+// interval is given.
+const DefaultCoalesceInterval = 20 * time.Millisecond
+
+// Coalescer buffers outgoing messages keyed by destination and OSC address
+// and sends the latest message of every key at a fixed interval ("latest value
+// wins"), e.g. for faders and meters. The latest message of every key is
+// always sent, at the latest by Flush or Close.
+type Coalescer struct {
+	// Bundle packs all messages of a destination into one bundle per flush.
+	// Bundles exceeding the maximum packet size of the node are split.
+	Bundle bool
+	// SendFailed is called, if sending to a destination fails.
+	SendFailed func(addr net.Addr, err error)
+
+	node  *Node
+	mutex sync.Mutex
+	// sendMutex serializes flushes, so older messages aren't sent after newer
+	// ones
+	sendMutex sync.Mutex
+	// pending messages by destination in order of the first message
+	pending []*coalesceDest
+	closed  bool
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// coalesceDest holds the pending messages of a destination.
+type coalesceDest struct {
+	addr net.Addr
+	// messages in order of the first message of every address
+	msgs []*Message
+}
+
+// NewCoalescer returns a Coalescer, that sends with node every interval. An
+// interval <= 0 means DefaultCoalesceInterval.
+func NewCoalescer(node *Node, interval time.Duration) *Coalescer {
+	if interval <= 0 {
+		interval = DefaultCoalesceInterval
+	}
+	c := &Coalescer{
+		node: node,
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	go c.run(interval)
+	return c
+}
+
+// run flushes every interval until the coalescer is closed.
+func (c *Coalescer) run(interval time.Duration) {
+	defer close(c.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			_ = c.Flush()
+		case <-c.stop:
+			return
+		}
+	}
+}
+
+// SendTo buffers the message msg to raddr. A buffered message with the same
+// destination and address is replaced.
+func (c *Coalescer) SendTo(raddr string, msg *Message) error {
+	addr, err := c.node.resolve(raddr)
+	if err != nil {
+		return err
+	}
+	return c.SendToAddr(addr, msg)
+}
+
+// SendToAddr buffers the message msg to addr (see SendTo).
+func (c *Coalescer) SendToAddr(addr net.Addr, msg *Message) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.closed {
+		return ErrorNodeClosed
+	}
+
+	var dest *coalesceDest
+	for _, d := range c.pending {
+		if sameAddr(d.addr, addr) {
+			dest = d
+			break
+		}
+	}
+	if dest == nil {
+		dest = &coalesceDest{addr: addr}
+		c.pending = append(c.pending, dest)
+	}
+
+	for i, m := range dest.msgs {
+		if m.Address == msg.Address {
+			dest.msgs[i] = msg
+			return nil
+		}
+	}
+	dest.msgs = append(dest.msgs, msg)
+	return nil
+}
+
+// SendMsgTo buffers an OSC Message to raddr (all int types converted to
+// int32, see Node.SendMsgTo).
+func (c *Coalescer) SendMsgTo(raddr string, path string, args ...any) error {
+	msg, err := newMessageFromArgs(path, args...)
+	if err != nil {
+		return err
+	}
+	return c.SendTo(raddr, msg)
+}
+
+// Flush sends all buffered messages now. If sending fails for some
+// destinations, SendErrors is returned.
+func (c *Coalescer) Flush() error {
+	c.sendMutex.Lock()
+	defer c.sendMutex.Unlock()
+
+	c.mutex.Lock()
+	pending := c.pending
+	c.pending = nil
+	c.mutex.Unlock()
+
+	var errs SendErrors
+	for _, dest := range pending {
+		if err := c.send(dest); err != nil {
+			errs = append(errs, &DestinationError{Addr: dest.addr, Err: err})
+			if c.SendFailed != nil {
+				c.SendFailed(dest.addr, err)
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// send sends the messages of dest.
+func (c *Coalescer) send(dest *coalesceDest) error {
+	if !c.Bundle {
+		var errs []error
+		for _, msg := range dest.msgs {
+			errs = append(errs, c.node.SendToAddr(dest.addr, msg))
+		}
+		return errors.Join(errs...)
+	}
+
+	// the bundles must fit into a packet after signing
+	size := c.node.maxPacketSize()
+	if c.node.Auth != nil {
+		size -= c.node.Auth.Overhead()
+	}
+	bundle := &Bundle{Timetag: NewImmediateTimetag(), Messages: dest.msgs}
+	bundles, err := bundle.Split(size)
+	if err != nil {
+		return err
+	}
+	for _, b := range bundles {
+		if err := c.node.SendToAddr(dest.addr, b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close sends all buffered messages and stops the coalescer. The node isn't
+// closed.
+func (c *Coalescer) Close() error {
+	c.mutex.Lock()
+	if c.closed {
+		c.mutex.Unlock()
+		return nil
+	}
+	c.closed = true
+	c.mutex.Unlock()
+
+	close(c.stop)
+	<-c.done
+	return c.Flush()
+}
@@ -0,0 +1,163 @@
+package osc_test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"bekuba.de/go-osc"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCoalescer(t *testing.T) {
+	sender, err := osc.NewNode("127.0.0.1:0")
+	assert.NoError(t, err)
+	defer sender.Close()
+
+	node, received := newReceiver(t)
+	addr := node.Conn().LocalAddr().String()
+
+	c := osc.NewCoalescer(sender, time.Hour)
+	for i := int32(0); i < 100; i++ {
+		assert.NoError(t, c.SendMsgTo(addr, "/fader/1", i))
+	}
+	assert.NoError(t, c.SendMsgTo(addr, "/meter/1", float32(0.5)))
+	assert.NoError(t, c.SendMsgTo(addr, "/fader/1", int32(100)))
+	assert.NoError(t, c.Flush())
+
+	// only the latest value, in order of the first message
+	for _, expected := range []*osc.Message{
+		osc.NewMessage("/fader/1", int32(100)),
+		osc.NewMessage("/meter/1", float32(0.5)),
+	} {
+		select {
+		case msg := <-received:
+			assert.Equal(t, expected, msg)
+		case <-time.After(time.Second):
+			t.Fatal("timeout")
+		}
+	}
+
+	// the final value is sent on close
+	assert.NoError(t, c.SendMsgTo(addr, "/fader/1", int32(0)))
+	assert.NoError(t, c.Close())
+	select {
+	case msg := <-received:
+		assert.Equal(t, osc.NewMessage("/fader/1", int32(0)), msg)
+	case <-time.After(time.Second):
+		t.Fatal("timeout")
+	}
+	select {
+	case msg := <-received:
+		t.Fatalf("unexpected message %v", msg)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	assert.ErrorIs(t, c.SendMsgTo(addr, "/fader/1", int32(1)), osc.ErrorNodeClosed)
+	assert.ErrorIs(t, c.SendMsgTo("localhost", "/fader/1", int32(1)), osc.ErrorOscAddressFormat)
+}
+
+func TestCoalescerBundle(t *testing.T) {
+	sender, err := osc.NewNode("127.0.0.1:0")
+	assert.NoError(t, err)
+	defer sender.Close()
+
+	received := make(packetReceiver, 10)
+	node := newServingNode(t, received, nil)
+	addr := node.Conn().LocalAddr().String()
+
+	c := osc.NewCoalescer(sender, 10*time.Millisecond)
+	c.Bundle = true
+	defer c.Close()
+
+	for i := int32(0); i < 10; i++ {
+		assert.NoError(t, c.SendMsgTo(addr, "/fader/1", i))
+		assert.NoError(t, c.SendMsgTo(addr, "/fader/2", -i))
+	}
+
+	// flushed by the ticker
+	select {
+	case packet := <-received:
+		bundle, ok := packet.(*osc.Bundle)
+		assert.True(t, ok)
+		assert.Equal(t, []*osc.Message{
+			osc.NewMessage("/fader/1", int32(9)),
+			osc.NewMessage("/fader/2", int32(-9)),
+		}, bundle.Messages)
+	case <-time.After(time.Second):
+		t.Fatal("timeout")
+	}
+}
+
+func TestCoalescerConcurrentFlush(t *testing.T) {
+	sender, err := osc.NewNode("127.0.0.1:0")
+	assert.NoError(t, err)
+	defer sender.Close()
+
+	node, received := newReceiver(t)
+	addr := node.Conn().LocalAddr().String()
+
+	// the default interval races with the flushes
+	c := osc.NewCoalescer(sender, 0)
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				_ = c.Flush()
+			}
+		}()
+	}
+	for i := int32(1); i <= 500; i++ {
+		assert.NoError(t, c.SendMsgTo(addr, "/fader/1", i))
+	}
+	wg.Wait()
+	assert.NoError(t, c.Close())
+
+	// the values are received in order
+	var last int32
+	for {
+		select {
+		case msg := <-received:
+			v := msg.Arguments[0].(int32)
+			assert.Greater(t, v, last)
+			last = v
+			continue
+		case <-time.After(50 * time.Millisecond):
+		}
+		break
+	}
+	assert.Equal(t, int32(500), last)
+}
+
+func TestCoalescerAuth(t *testing.T) {
+	key := []byte("secret")
+	node, received, rejected := newAuthNode(t, key)
+	addr := node.Conn().LocalAddr().String()
+
+	sender, err := osc.NewNode("127.0.0.1:0")
+	assert.NoError(t, err)
+	defer sender.Close()
+	sender.MaxPacketSize = 256
+	sender.Auth = osc.NewAuthenticator(key)
+
+	c := osc.NewCoalescer(sender, time.Hour)
+	c.Bundle = true
+	defer c.Close()
+
+	// the signed bundles fit into the maximum packet size
+	for i := int32(0); i < 20; i++ {
+		assert.NoError(t, c.SendMsgTo(addr, fmt.Sprintf("/fader/%d", i), i))
+	}
+	assert.NoError(t, c.Flush())
+
+	for i := int32(0); i < 20; i++ {
+		msg, err := expectMessage(t, received, rejected)
+		if assert.NoError(t, err) {
+			assert.Equal(t, osc.NewMessage(fmt.Sprintf("/fader/%d", i), i), msg)
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package osc
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+)
+
+// DestinationGroup is a group of remote addresses, that get the same OSC
+// packets, e.g. meter updates for all connected tablets. The members are a
+// static list of addresses and, optionally, the active peers of a
+// PeerRegistry.
+type DestinationGroup struct {
+	node *Node
+
+	mutex sync.Mutex
+	addrs []net.Addr
+	peers *PeerRegistry
+}
+
+// NewDestinationGroup returns a DestinationGroup, that sends with node to the
+// addresses raddrs.
+func NewDestinationGroup(node *Node, raddrs ...string) (*DestinationGroup, error) {
+	g := &DestinationGroup{node: node}
+	for _, raddr := range raddrs {
+		if err := g.Add(raddr); err != nil {
+			return nil, err
+		}
+	}
+	return g, nil
+}
+
+// Add adds the remote address raddr to the group. raddr may be a broadcast
+// address, e.g. "192.168.1.255:8000", then sending to broadcast addresses is
+// enabled for the node (see Node.SetBroadcast). Go enables it for UDP sockets
+// on most platforms anyway, this is a safeguard for the others.
+func (g *DestinationGroup) Add(raddr string) error {
+	addr, err := g.node.resolve(raddr)
+	if err != nil {
+		return err
+	}
+	if isBroadcast(addr) {
+		if err := g.node.SetBroadcast(true); err != nil && !errors.Is(err, ErrorNotSupported) {
+			return err
+		}
+	}
+
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	for _, a := range g.addrs {
+		if sameAddr(a, addr) {
+			return nil
+		}
+	}
+	g.addrs = append(g.addrs, addr)
+	return nil
+}
+
+// Remove removes the remote address raddr from the group.
+func (g *DestinationGroup) Remove(raddr string) error {
+	addr, err := g.node.resolve(raddr)
+	if err != nil {
+		return err
+	}
+
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	for i, a := range g.addrs {
+		if sameAddr(a, addr) {
+			g.addrs = append(g.addrs[:i], g.addrs[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// SetPeers adds all active peers of the registry to the group. A nil
+// registry removes the peers.
+func (g *DestinationGroup) SetPeers(peers *PeerRegistry) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	g.peers = peers
+}
+
+// Addrs returns the addresses of all members of the group.
+func (g *DestinationGroup) Addrs() []net.Addr {
+	g.mutex.Lock()
+	addrs := append([]net.Addr{}, g.addrs...)
+	peers := g.peers
+	g.mutex.Unlock()
+
+	if peers == nil {
+		return addrs
+	}
+
+	for _, p := range peers.Addrs() {
+		exists := false
+		for _, a := range addrs {
+			if sameAddr(a, p) {
+				exists = true
+				break
+			}
+		}
+		if !exists {
+			addrs = append(addrs, p)
+		}
+	}
+	return addrs
+}
+
+// SendToAll sends an OSC Bundle or an OSC Message to all members of the
+// group. The packet is marshaled once. If sending fails for some members,
+// SendErrors with the error of every failed member is returned.
+func (g *DestinationGroup) SendToAll(packet Packet) error {
+	data, err := packet.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	var errs SendErrors
+	for _, addr := range g.Addrs() {
+		if err := g.node.writeTo(data, addr); err != nil {
+			errs = append(errs, &DestinationError{Addr: addr, Err: err})
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// SendMsgToAll sends an OSC Message to all members of the group (all int
+// types converted to int32, see SendMsgTo).
+func (g *DestinationGroup) SendMsgToAll(path string, args ...any) error {
+	msg, err := newMessageFromArgs(path, args...)
+	if err != nil {
+		return err
+	}
+	return g.SendToAll(msg)
+}
+
+// DestinationError is the error of sending a packet to one destination.
+type DestinationError struct {
+	Addr net.Addr
+	Err  error
+}
+
+// Error implements the error interface.
+func (e *DestinationError) Error() string {
+	return fmt.Sprintf("send to %v: %v", e.Addr, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *DestinationError) Unwrap() error {
+	return e.Err
+}
+
+// SendErrors holds the errors of all destinations, that failed.
+type SendErrors []*DestinationError
+
+// Error implements the error interface.
+func (e SendErrors) Error() string {
+	s := make([]string, len(e))
+	for i, err := range e {
+		s[i] = err.Error()
+	}
+	return strings.Join(s, "; ")
+}
+
+// Unwrap returns the errors of all destinations.
+func (e SendErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, err := range e {
+		errs[i] = err
+	}
+	return errs
+}
+
+// isBroadcast returns true for the limited broadcast address and the
+// broadcast addresses of the IPv4 networks of the local interfaces.
+func isBroadcast(addr net.Addr) bool {
+	udpAddr, ok := addr.(*net.UDPAddr)
+	if !ok || udpAddr.IP.To4() == nil {
+		return false
+	}
+	ip := udpAddr.IP.To4()
+	if ip.Equal(net.IPv4bcast) {
+		return true
+	}
+
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return false
+	}
+	for _, a := range addrs {
+		ipNet, ok := a.(*net.IPNet)
+		if !ok || ipNet.IP.To4() == nil || len(ipNet.Mask) != net.IPv4len {
+			continue
+		}
+		broadcast := make(net.IP, net.IPv4len)
+		for i := range broadcast {
+			broadcast[i] = ipNet.IP.To4()[i] | ^ipNet.Mask[i]
+		}
+		if ip.Equal(broadcast) && !ip.Equal(ipNet.IP.To4()) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,38 @@
+//go:build unix
+
+package osc_test
+
+import (
+	"errors"
+	"net"
+	"syscall"
+	"testing"
+
+	"bekuba.de/go-osc"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBroadcast(t *testing.T) {
+	node, err := osc.NewNode("0.0.0.0:0")
+	assert.NoError(t, err)
+	defer node.Close()
+
+	group, err := osc.NewDestinationGroup(node, "255.255.255.255:9")
+	assert.NoError(t, err)
+
+	assert.NoError(t, node.SetBroadcast(false))
+	err = group.SendMsgToAll("/cue/go")
+	assert.ErrorIs(t, err, syscall.EACCES)
+
+	// adding a broadcast address enables broadcast
+	assert.NoError(t, group.Add("255.255.255.255:9"))
+	err = group.SendMsgToAll("/cue/go")
+	if errors.Is(err, syscall.ENETUNREACH) {
+		t.Skip("no route for broadcast")
+	}
+	assert.NoError(t, err)
+
+	var addr net.Addr = group.Addrs()[0]
+	assert.Equal(t, "255.255.255.255:9", addr.String())
+}
@@ -0,0 +1,102 @@
+package osc_test
+
+import (
+	"testing"
+	"time"
+
+	"bekuba.de/go-osc"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDestinationGroup(t *testing.T) {
+	sender, err := osc.NewNode("127.0.0.1:0")
+	assert.NoError(t, err)
+	defer sender.Close()
+
+	var addrs []string
+	var channels []chan *osc.Message
+	for i := 0; i < 3; i++ {
+		node, received := newReceiver(t)
+		addrs = append(addrs, node.Conn().LocalAddr().String())
+		channels = append(channels, received)
+	}
+
+	expect := func(received chan *osc.Message, msg *osc.Message) {
+		select {
+		case m := <-received:
+			assert.Equal(t, msg, m)
+		case <-time.After(time.Second):
+			t.Fatalf("%v not received", msg)
+		}
+	}
+
+	group, err := osc.NewDestinationGroup(sender, addrs[0], addrs[1])
+	assert.NoError(t, err)
+
+	// duplicates are ignored
+	assert.NoError(t, group.Add(addrs[1]))
+	assert.Equal(t, 2, len(group.Addrs()))
+
+	t.Run("should send to all members", func(t *testing.T) {
+		err := group.SendMsgToAll("/meter", float32(0.5))
+		assert.NoError(t, err)
+
+		expect(channels[0], osc.NewMessage("/meter", float32(0.5)))
+		expect(channels[1], osc.NewMessage("/meter", float32(0.5)))
+	})
+
+	t.Run("should send to active peers", func(t *testing.T) {
+		peers := osc.NewPeerRegistry(0)
+		sender.Peers = peers
+		group.SetPeers(peers)
+		go sender.ListenAndServe(nil)
+
+		// node 3 joins by sending a message
+		client, err := osc.NewNode("127.0.0.1:0")
+		assert.NoError(t, err)
+		defer client.Close()
+		assert.NoError(t, client.SendMsgTo(sender.Conn().LocalAddr().String(), "/hello"))
+		for len(peers.Peers()) == 0 {
+			time.Sleep(time.Millisecond)
+		}
+
+		assert.NoError(t, group.Remove(addrs[0]))
+		assert.NoError(t, group.Add(addrs[2]))
+		assert.Equal(t, 3, len(group.Addrs()))
+
+		err = group.SendToAll(osc.NewMessage("/meter", float32(1)))
+		assert.NoError(t, err)
+
+		expect(channels[1], osc.NewMessage("/meter", float32(1)))
+		expect(channels[2], osc.NewMessage("/meter", float32(1)))
+
+		client.ReadTimeout = time.Second
+		p, _, err := client.Read()
+		assert.NoError(t, err)
+		assert.Equal(t, osc.NewMessage("/meter", float32(1)), p)
+
+		select {
+		case m := <-channels[0]:
+			t.Errorf("unexpected message %v", m)
+		default:
+		}
+	})
+
+	t.Run("should collect errors per destination", func(t *testing.T) {
+		sender.MaxPacketSize = 32
+		err := group.SendToAll(osc.NewMessage("/meter", make([]byte, 32)))
+		sender.MaxPacketSize = 0
+
+		var errs osc.SendErrors
+		if assert.ErrorAs(t, err, &errs) {
+			assert.Equal(t, 3, len(errs))
+		}
+		assert.ErrorIs(t, err, osc.ErrorPacketTooLarge)
+
+		var destErr *osc.DestinationError
+		if assert.ErrorAs(t, err, &destErr) {
+			assert.Equal(t, addrs[1], destErr.Addr.String())
+		}
+	})
+}
@@ -1,42 +1,34 @@
 package osc
 
 import (
-	"bufio"
 	"bytes"
 	"encoding/binary"
 	"fmt"
+	"io"
 	"strings"
 )
 
-// readBlob reads an OSC blob from the blob byte array. Padding bytes are
-// removed from the reader and not returned.
-func readBlob(reader *bufio.Reader) ([]byte, int, error) {
+// readBlob reads an OSC blob from data. Padding bytes are skipped and not
+// returned. The returned int is the number of read bytes.
+func readBlob(data []byte) ([]byte, int, error) {
 	// First, get the length
-	var blobLen int32
-	if err := binary.Read(reader, binary.BigEndian, &blobLen); err != nil {
-		return nil, 0, err
+	if len(data) < 4 {
+		return nil, 0, io.ErrUnexpectedEOF
 	}
-	n := 4 + int(blobLen)
+	blobLen := int32(binary.BigEndian.Uint32(data))
 
-	if blobLen < 1 || blobLen > int32(reader.Buffered()) {
+	if blobLen < 0 || int(blobLen) > len(data)-4 {
 		return nil, 0, fmt.Errorf("readBlob: invalid blob length %d", blobLen)
 	}
 
-	// Read the data
-	blob := make([]byte, blobLen)
-	if _, err := reader.Read(blob); err != nil {
-		return nil, 0, err
+	n := 4 + int(blobLen) + padBytesNeeded(int(blobLen))
+	if n > len(data) {
+		return nil, 0, io.ErrUnexpectedEOF
 	}
 
-	// Remove the padding bytes
-	numPadBytes := padBytesNeeded(int(blobLen))
-	if numPadBytes > 0 {
-		n += numPadBytes
-		dummy := make([]byte, numPadBytes)
-		if _, err := reader.Read(dummy); err != nil {
-			return nil, 0, err
-		}
-	}
+	// Copy the data, data may be reused by the caller
+	blob := make([]byte, blobLen)
+	copy(blob, data[4:])
 
 	return blob, n, nil
 }
@@ -71,29 +63,23 @@ func writeBlob(data []byte, buf *bytes.Buffer) (int, error) {
 	return 4 + lenData + numPadBytes, nil
 }
 
-// readPaddedString reads a padded string from the given reader. The padding
-// bytes are removed from the reader.
-func readPaddedString(reader *bufio.Reader) (string, int, error) {
-	// Read the string from the reader
-	str, err := reader.ReadString(0)
-	if err != nil {
-		return "", 0, err
+// readPaddedString reads a padded string from data. The returned int is the
+// number of read bytes including the null delimiter and the padding bytes.
+func readPaddedString(data []byte) (string, int, error) {
+	// Find the string delimiter
+	lenStr := bytes.IndexByte(data, 0)
+	if lenStr < 0 {
+		return "", 0, io.EOF
 	}
-	lenStr := len(str)
-	n := lenStr
-
-	// Remove the padding bytes (leaving the null delimiter)
-	padLen := padBytesNeeded(lenStr)
-	if padLen > 0 {
-		n += padLen
-		padBytes := make([]byte, padLen)
-		if _, err = reader.Read(padBytes); err != nil {
-			return "", 0, err
-		}
+
+	// Skip the padding bytes
+	n := lenStr + 1
+	n += padBytesNeeded(n)
+	if n > len(data) {
+		return "", 0, io.ErrUnexpectedEOF
 	}
 
-	// Strip off the string delimiter
-	return str[:lenStr-1], n, nil
+	return string(data[:lenStr]), n, nil
 }
 
 // writePaddedString writes a string with padding bytes to the a buffer.
@@ -101,7 +87,7 @@ func readPaddedString(reader *bufio.Reader) (string, int, error) {
 func writePaddedString(str string, buf *bytes.Buffer) (int, error) {
 	// Truncate at the first null, just in case there is more than one present
 	nullIndex := strings.Index(str, "\x00")
-	if nullIndex > 0 {
+	if nullIndex >= 0 {
 		str = str[:nullIndex]
 	}
 	// Write the string to the buffer
@@ -78,6 +78,7 @@ func TestWritePaddedString(t *testing.T) {
 		{"tes\x00\x00\x00\x00\x00", []byte{'t', 'e', 's', 0}, 4}, // Skip extra nulls
 		{"tes\x00\x00\x00", []byte{'t', 'e', 's', 0}, 4},         // Even if they don't fall on a 4 byte padding boundary
 		{"", []byte{0, 0, 0, 0}, 4},                              // OSC uses null terminated strings, padded to the 4 byte boundary
+		{"\x00tes", []byte{0, 0, 0, 0}, 4},                       // Truncate at a leading null
 	} {
 		buf := []byte{}
 		bytesBuffer := bytes.NewBuffer(buf)
@@ -137,6 +138,8 @@ func TestReadBlob(t *testing.T) {
 		{"negative value", []byte{255, 255, 255, 255}, nil, 0, true},
 		{"large value", []byte{0, 1, 17, 112}, nil, 0, true},
 		{"regular value", []byte{0, 0, 0, 1, 10, 0, 0, 0}, []byte{10}, 8, false},
+		{"zero length", []byte{0, 0, 0, 0}, []byte{}, 4, false},
+		{"missing padding", []byte{0, 0, 0, 1, 10}, nil, 0, true},
 	} {
 		t.Run(tt.name, func(t *testing.T) {
 			got, got1, err := osc.ReadBlob(bufio.NewReader(bytes.NewBuffer(tt.args)))
@@ -1,6 +1,9 @@
 package osc
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 // OSC Errors
 var (
@@ -10,4 +13,47 @@ var (
 	ErrorOscAddressExists    = errors.New("OSC address exists already")
 	ErrorUnsuportedPackage   = errors.New("unsupported OSC packet type: only Bundle and Message are supported")
 	ErrorInvalidPacked       = errors.New("invalid OSC packet")
+	ErrorPacketTooLarge      = errors.New("OSC packet exceeds the maximum packet size")
+	ErrorInvalidPadding      = errors.New("OSC padding bytes must be zero")
+	ErrorInvalidAlignment    = errors.New("OSC packet size is not a multiple of 4")
+	ErrorTrailingBytes       = errors.New("trailing bytes after OSC packet")
+	ErrorMissingTypeTags     = errors.New("OSC message without type tag string")
+	ErrorLimitExceeded       = errors.New("OSC decode limit exceeded")
+	ErrorRequestTimeout      = errors.New("OSC request timed out")
+	ErrorNotAcknowledged     = errors.New("OSC packet was not acknowledged")
+	ErrorNodeClosed          = errors.New("OSC node is closed")
+	ErrorNotSupported        = errors.New("not supported on this platform")
+	ErrorWebSocketHandshake  = errors.New("WebSocket handshake failed")
+	ErrorWebSocketProtocol   = errors.New("WebSocket protocol error")
+	ErrorAuthentication      = errors.New("OSC packet authentication failed")
+	ErrorQueueFull           = errors.New("OSC send queue is full")
+	ErrorPacketsLost         = errors.New("OSC packets were dropped or not sent")
+	ErrorInvalidRecording    = errors.New("invalid OSC recording")
+	ErrorInvalidCapture      = errors.New("invalid pcap capture")
+	ErrorInvalidSyntax       = errors.New("invalid OSC text syntax")
+	ErrorInvalidArgument     = errors.New("invalid OSC argument")
 )
+
+// DecodeError is returned if a received OSC packet can't be decoded. Offset is
+// the position in the packet (in bytes) where decoding failed and Context
+// names the element, that was decoded, e.g. "address" or "argument 2 (f)".
+type DecodeError struct {
+	Offset  int
+	Context string
+	Err     error
+}
+
+// Error implements the error interface.
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("invalid OSC packet: %s at offset %d: %v", e.Context, e.Offset, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports a DecodeError as ErrorInvalidPacked.
+func (e *DecodeError) Is(target error) bool {
+	return target == ErrorInvalidPacked
+}
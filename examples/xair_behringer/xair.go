@@ -1,8 +1,11 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"net"
+	"os"
+	"os/signal"
 	"time"
 
 	"bekuba.de/go-osc"
@@ -44,19 +47,47 @@ func main() {
 		}
 	}()
 
-	app.SendMsgTo(addr, "/xinfo")
+	// wait for the reply of /xinfo
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	info, err := app.Request(ctx, addr, osc.NewMessage("/xinfo"), nil)
+	cancel()
+	if err != nil {
+		fmt.Println(err)
+	} else if name, err := info.Arguments.Str(1); err != nil {
+		fmt.Printf("invalid reply %v: %v\n", info, err)
+	} else {
+		fmt.Printf("mixer %v found\n", name)
+	}
 
-	for {
-		// keepp connection alive (for multi client usage)
-		app.SendMsgTo(addr, "/xremote")
-		// show status of xair
-		app.SendMsgTo(addr, "/status")
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
 
-		time.Sleep(1 * time.Second)
+	// keep connection alive (for multi client usage)
+	sub, err := app.Subscribe(ctx, addr, osc.NewMessage("/xremote"), osc.SubscriptionOptions{
+		OnConnected:    func(addr net.Addr) { fmt.Printf("xr %v connected\n", addr) },
+		OnDisconnected: func(addr net.Addr) { fmt.Printf("xr %v disconnected\n", addr) },
+	})
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	// show status of xair
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-sub.Done():
+			return
+		case <-ticker.C:
+			app.SendMsgTo(addr, "/status")
+		}
 	}
 
 	// Output:
 	//	xr 10.0.1.174:10024: /xinfo ,ssss 10.0.1.174 XR18-35-54-8A XR18 1.22
+	//	mixer XR18-35-54-8A found
+	//	xr 10.0.1.174:10024 connected
 	//	xr 10.0.1.174:10024: /status ,sss active 10.0.1.174 XR18-35-54-8A
 	//	xr 10.0.1.174:10024: /status ,sss active 10.0.1.174 XR18-35-54-8A
 	//	xr 10.0.1.174:10024: /status ,sss active 10.0.1.174 XR18-35-54-8A
@@ -0,0 +1,163 @@
+package osc_test
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"bekuba.de/go-osc"
+)
+
+// addCorpus adds the recorded real-world packets in testdata/packets to the
+// seed corpus of f.
+func addCorpus(f *testing.F) {
+	files, err := filepath.Glob(filepath.Join("testdata", "packets", "*.osc"))
+	if err != nil {
+		f.Fatal(err)
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(data)
+	}
+}
+
+func FuzzUnmarshalPacket(f *testing.F) {
+	addCorpus(f)
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		for _, opts := range []osc.DecodeOptions{
+			{},
+			{Strict: true},
+			{Lenient: true},
+		} {
+			p, err := opts.UnmarshalPacket(data)
+			if err != nil {
+				continue
+			}
+
+			// a decoded packet must survive an encode/decode round-trip
+			data1, err := p.MarshalBinary()
+			if err != nil {
+				t.Fatalf("%+v: can't marshal decoded packet: %v", opts, err)
+			}
+			if len(data1)%4 != 0 {
+				t.Fatalf("%+v: marshaled packet size %d is not a multiple of 4", opts, len(data1))
+			}
+
+			p1, err := opts.UnmarshalPacket(data1)
+			if err != nil {
+				t.Fatalf("%+v: can't decode marshaled packet: %v", opts, err)
+			}
+
+			data2, err := p1.MarshalBinary()
+			if err != nil {
+				t.Fatalf("%+v: can't marshal decoded packet: %v", opts, err)
+			}
+			if !bytes.Equal(data1, data2) {
+				t.Fatalf("%+v: round-trip mismatch\n%q\n%q", opts, data1, data2)
+			}
+		}
+	})
+}
+
+func FuzzMessageRoundTrip(f *testing.F) {
+	f.Add("/ch/01/mix/fader", int32(1), int64(2), float32(0.5), float64(0.25), "text", []byte{1, 2, 3}, uint64(1), true)
+
+	f.Fuzz(func(t *testing.T, addr string, i int32, h int64, fl float32, d float64, s string, b []byte, tt uint64, flag bool) {
+		msg := osc.NewMessage("/"+addr, i, h, fl, d, s, b, osc.Timetag(tt), flag, nil)
+
+		data, err := msg.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		p, err := osc.UnmarshalPacket(data)
+		if err != nil {
+			t.Fatalf("can't decode %q: %v", data, err)
+		}
+
+		data1, err := p.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, data1) {
+			t.Fatalf("round-trip mismatch\n%q\n%q", data, data1)
+		}
+	})
+}
+
+func TestCorpus(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "packets", "*.osc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := osc.UnmarshalPacket(data); err != nil {
+			t.Errorf("%s: %v", file, err)
+		}
+	}
+}
+
+func FuzzPcapReader(f *testing.F) {
+	var buf bytes.Buffer
+	w, err := osc.NewPcapWriter(&buf)
+	if err != nil {
+		f.Fatal(err)
+	}
+	src := &net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 9000}
+	for _, dst := range []*net.UDPAddr{
+		{IP: net.IPv4(10, 0, 0, 2), Port: 8000},
+		{IP: net.ParseIP("fd00::2"), Port: 8000},
+	} {
+		if err := w.WritePacket(time.Now(), src, dst, osc.NewMessage("/cue/go", int32(1))); err != nil {
+			f.Fatal(err)
+		}
+	}
+	f.Add(buf.Bytes())
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		r, err := osc.NewPcapReader(bytes.NewReader(data))
+		if err != nil {
+			return
+		}
+		for i := 0; i < 100; i++ {
+			if _, err := r.Next(); err != nil {
+				return
+			}
+		}
+	})
+}
+
+func FuzzParsePacket(f *testing.F) {
+	f.Add(`/a ,ifsbhtdTFN 1 2.5 "text" [1 2 3] 4 5 0.25`)
+	f.Add(`/a 1 0.5 "text" hex:ff base64:AQ== true nil word`)
+	f.Add(`#bundle immediate { /a ,i 1; #bundle 1 { /b untyped [0 0 0 1] } }`)
+
+	f.Fuzz(func(t *testing.T, s string) {
+		p, err := osc.ParsePacket(s)
+		if err != nil {
+			return
+		}
+
+		// a parsed packet must survive a format/parse round-trip
+		text := p.(fmt.Stringer).String()
+		p2, err := osc.ParsePacket(text)
+		if err != nil {
+			t.Fatalf("can't parse %q of %q: %v", text, s, err)
+		}
+		if text2 := p2.(fmt.Stringer).String(); text2 != text {
+			t.Fatalf("round-trip of %q: %q != %q", s, text2, text)
+		}
+	})
+}
@@ -0,0 +1,82 @@
+package osc_test
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"bekuba.de/go-osc"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// packetReceiver is a Dispatcher, that forwards all received packets.
+type packetReceiver chan osc.Packet
+
+func (r packetReceiver) Dispatch(packet osc.Packet, addr net.Addr) error {
+	r <- packet
+	return nil
+}
+
+// newMessageReceiver returns a dispatcher, that sends all received messages
+// to the returned channel.
+func newMessageReceiver(t *testing.T) (*osc.StandardDispatcher, chan *osc.Message) {
+	received := make(chan *osc.Message, 100)
+	d := osc.NewStandardDispatcher()
+	err := d.AddMsgHandler("*", func(msg *osc.Message) { received <- msg })
+	assert.NoError(t, err)
+	return d, received
+}
+
+// newServingNode returns a node on a random local UDP port serving d, that is
+// closed at the end of the test. If setup is not nil, it is called before the
+// node is served.
+func newServingNode(t *testing.T, d osc.Dispatcher, setup func(node *osc.Node)) *osc.Node {
+	node, err := osc.NewNode("127.0.0.1:0")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	t.Cleanup(node.Close)
+
+	if setup != nil {
+		setup(node)
+	}
+	go node.ListenAndServe(d)
+	return node
+}
+
+// newReceiver returns a serving node and a channel with all received messages.
+func newReceiver(t *testing.T) (*osc.Node, chan *osc.Message) {
+	d, received := newMessageReceiver(t)
+	return newServingNode(t, d, nil), received
+}
+
+// newStreamServer returns a StreamServer listening on a random local TCP port.
+func newStreamServer(t *testing.T, d osc.Dispatcher, framing osc.Framing) (*osc.StreamServer, string) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	server := osc.NewStreamServer(d, framing)
+	go server.Serve(l)
+	t.Cleanup(server.Close)
+	return server, l.Addr().String()
+}
+
+// newWebSocketServer returns a WebSocket server on "/osc" sharing the port
+// with a plain HTTP handler on "/".
+func newWebSocketServer(t *testing.T, d osc.Dispatcher) (*osc.WebSocketServer, *httptest.Server) {
+	ws := osc.NewWebSocketServer(d)
+	mux := http.NewServeMux()
+	mux.Handle("/osc", ws)
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "hello")
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(func() {
+		ws.Close()
+		server.Close()
+	})
+	return ws, server
+}
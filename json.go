@@ -0,0 +1,381 @@
+package osc
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// jsonMessage is the JSON and YAML form of a message.
+type jsonMessage struct {
+	Address   string         `json:"address" yaml:"address"`
+	Arguments []jsonArgument `json:"arguments,omitempty" yaml:"arguments,omitempty"`
+	Untyped   bool           `json:"untyped,omitempty" yaml:"untyped,omitempty"`
+	// base64 encoded RawArguments of untyped messages
+	Raw string `json:"raw,omitempty" yaml:"raw,omitempty"`
+}
+
+// jsonArgument is the JSON and YAML form of an argument. The values of T, F
+// and N are omitted.
+type jsonArgument struct {
+	Type  string `json:"type" yaml:"type"`
+	Value any    `json:"value,omitempty" yaml:"value,omitempty"`
+}
+
+// jsonBundle is the JSON and YAML form of a bundle.
+type jsonBundle struct {
+	Timetag  Timetag    `json:"timetag" yaml:"timetag"`
+	Messages []*Message `json:"messages,omitempty" yaml:"messages,omitempty"`
+	Bundles  []*Bundle  `json:"bundles,omitempty" yaml:"bundles,omitempty"`
+}
+
+// MarshalJSON implements the json.Marshaler interface. The arguments are
+// objects with the type tag and the value:
+//
+//	{"address":"/a","arguments":[{"type":"i","value":1},{"type":"T"}]}
+//
+// Blobs are base64 encoded, timetags are numbers and the non-finite floats
+// are the strings "NaN", "+Inf" and "-Inf".
+func (msg Message) MarshalJSON() ([]byte, error) {
+	v, err := msg.toJSON()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (msg *Message) UnmarshalJSON(data []byte) error {
+	var v jsonMessage
+	d := json.NewDecoder(bytes.NewReader(data))
+	// numbers are parsed by the type tags
+	d.UseNumber()
+	if err := d.Decode(&v); err != nil {
+		return err
+	}
+	return msg.fromJSON(&v)
+}
+
+// MarshalYAML returns the YAML form of the message, that has the structure of
+// the JSON form.
+func (msg Message) MarshalYAML() (any, error) {
+	return msg.toJSON()
+}
+
+// UnmarshalYAML implements the Unmarshaler interface of the YAML packages.
+func (msg *Message) UnmarshalYAML(unmarshal func(any) error) error {
+	var v jsonMessage
+	if err := unmarshal(&v); err != nil {
+		return err
+	}
+	return msg.fromJSON(&v)
+}
+
+// toJSON returns the JSON form of the message.
+func (msg *Message) toJSON() (*jsonMessage, error) {
+	v := &jsonMessage{Address: msg.Address}
+	if msg.Untyped {
+		v.Untyped = true
+		v.Raw = base64.StdEncoding.EncodeToString(msg.RawArguments)
+		return v, nil
+	}
+
+	for _, arg := range msg.Arguments {
+		a := jsonArgument{Type: string(getTypeTag(arg))}
+		switch t := arg.(type) {
+		case bool, nil:
+		case int32, int64, string:
+			a.Value = t
+		case float32:
+			a.Value = jsonFloat(float64(t), t)
+		case float64:
+			a.Value = jsonFloat(t, t)
+		case []byte:
+			a.Value = base64.StdEncoding.EncodeToString(t)
+		case Timetag:
+			a.Value = uint64(t)
+		default:
+			return nil, fmt.Errorf("unsupported type: %T", t)
+		}
+		v.Arguments = append(v.Arguments, a)
+	}
+	return v, nil
+}
+
+// jsonFloat returns the value v of f or the string of a non-finite f.
+func jsonFloat(f float64, v any) any {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+	return v
+}
+
+// fromJSON sets the message to the JSON form v.
+func (msg *Message) fromJSON(v *jsonMessage) error {
+	if !strings.HasPrefix(v.Address, "/") {
+		return fmt.Errorf("%w: %q", ErrorOscAddress, v.Address)
+	}
+	*msg = Message{Address: v.Address}
+
+	if v.Untyped {
+		raw, err := base64.StdEncoding.DecodeString(v.Raw)
+		if err != nil {
+			return fmt.Errorf("%w: raw arguments: %v", ErrorInvalidArgument, err)
+		}
+		msg.Untyped, msg.RawArguments = true, raw
+		return nil
+	}
+
+	for i, a := range v.Arguments {
+		arg, err := argumentFromJSON(a)
+		if err != nil {
+			return fmt.Errorf("argument %d: %w", i, err)
+		}
+		msg.Arguments = append(msg.Arguments, arg)
+	}
+	return nil
+}
+
+// argumentFromJSON returns the argument of the JSON or YAML form a. Numbers
+// are json.Number of JSON or the integer and float types of the YAML
+// packages.
+func argumentFromJSON(a jsonArgument) (any, error) {
+	switch a.Type {
+	case "T":
+		return true, nil
+	case "F":
+		return false, nil
+	case "N":
+		return nil, nil
+	}
+
+	var s string
+	switch t := a.Value.(type) {
+	case string:
+		s = t
+	case json.Number:
+		s = t.String()
+	case int, int64, uint64, float64:
+		s = fmt.Sprint(t)
+	case nil:
+		return nil, fmt.Errorf("%w: missing value of type %q", ErrorInvalidArgument, a.Type)
+	default:
+		return nil, fmt.Errorf("%w: value %v of type %q", ErrorInvalidArgument, t, a.Type)
+	}
+	_, isString := a.Value.(string)
+
+	var v any
+	var err error
+	switch a.Type {
+	case "s":
+		if !isString {
+			return nil, fmt.Errorf("%w: value %v of type %q", ErrorInvalidArgument, a.Value, a.Type)
+		}
+		return s, nil
+	case "b":
+		if !isString {
+			return nil, fmt.Errorf("%w: value %v of type %q", ErrorInvalidArgument, a.Value, a.Type)
+		}
+		v, err = base64.StdEncoding.DecodeString(s)
+	case "i":
+		if isString {
+			return nil, fmt.Errorf("%w: value %q of type %q", ErrorInvalidArgument, s, a.Type)
+		}
+		var i int64
+		i, err = strconv.ParseInt(s, 10, 32)
+		v = int32(i)
+	case "h":
+		if isString {
+			return nil, fmt.Errorf("%w: value %q of type %q", ErrorInvalidArgument, s, a.Type)
+		}
+		v, err = strconv.ParseInt(s, 10, 64)
+	case "f":
+		var f float64
+		f, err = parseFloatJSON(s, isString, 32)
+		v = float32(f)
+	case "d":
+		v, err = parseFloatJSON(s, isString, 64)
+	case "t":
+		var t uint64
+		t, err = strconv.ParseUint(s, 10, 64)
+		v = Timetag(t)
+		if err != nil && isString {
+			// "immediate", "now" or an RFC 3339 time
+			v, err = parseTimetagText(s)
+		}
+	default:
+		return nil, fmt.Errorf("%w: unsupported type tag %q", ErrorInvalidArgument, a.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: value %v of type %q", ErrorInvalidArgument, a.Value, a.Type)
+	}
+	return v, nil
+}
+
+// parseFloatJSON returns the float of a number or of the string of a
+// non-finite float.
+func parseFloatJSON(s string, isString bool, bitSize int) (float64, error) {
+	f, err := strconv.ParseFloat(s, bitSize)
+	if err == nil && isString && !math.IsNaN(f) && !math.IsInf(f, 0) {
+		return 0, strconv.ErrSyntax
+	}
+	return f, err
+}
+
+// MarshalJSON implements the json.Marshaler interface. The messages and
+// bundles are in the JSON form of Message.MarshalJSON:
+//
+//	{"timetag":1,"messages":[{"address":"/a"}],"bundles":[{"timetag":1}]}
+func (b Bundle) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonBundle{Timetag: b.Timetag, Messages: b.Messages, Bundles: b.Bundles})
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (b *Bundle) UnmarshalJSON(data []byte) error {
+	var v jsonBundle
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	return b.fromJSON(&v)
+}
+
+// MarshalYAML returns the YAML form of the bundle, that has the structure of
+// the JSON form.
+func (b Bundle) MarshalYAML() (any, error) {
+	return &jsonBundle{Timetag: b.Timetag, Messages: b.Messages, Bundles: b.Bundles}, nil
+}
+
+// UnmarshalYAML implements the Unmarshaler interface of the YAML packages.
+func (b *Bundle) UnmarshalYAML(unmarshal func(any) error) error {
+	var v jsonBundle
+	if err := unmarshal(&v); err != nil {
+		return err
+	}
+	return b.fromJSON(&v)
+}
+
+// fromJSON sets the bundle to the JSON form v.
+func (b *Bundle) fromJSON(v *jsonBundle) error {
+	for i, msg := range v.Messages {
+		if msg == nil {
+			return fmt.Errorf("%w: message %d is null", ErrorInvalidArgument, i)
+		}
+	}
+	for i, bundle := range v.Bundles {
+		if bundle == nil {
+			return fmt.Errorf("%w: bundle %d is null", ErrorInvalidArgument, i)
+		}
+	}
+
+	*b = Bundle{Timetag: v.Timetag, Messages: v.Messages, Bundles: v.Bundles}
+	if b.Messages == nil {
+		b.Messages = []*Message{}
+	}
+	if b.Bundles == nil {
+		b.Bundles = []*Bundle{}
+	}
+	return nil
+}
+
+// errUnknownJSONPacket is returned for JSON and YAML objects, that are neither
+// a message nor a bundle.
+var errUnknownJSONPacket = fmt.Errorf("%w: object without address or timetag", ErrorInvalidPacked)
+
+// UnmarshalPacketJSON returns the message or bundle of the JSON form of
+// Message.MarshalJSON or Bundle.MarshalJSON.
+func UnmarshalPacketJSON(data []byte) (Packet, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+
+	if _, ok := fields["timetag"]; ok {
+		b := &Bundle{}
+		if err := b.UnmarshalJSON(data); err != nil {
+			return nil, err
+		}
+		return b, nil
+	}
+	if _, ok := fields["address"]; ok {
+		msg := &Message{}
+		if err := msg.UnmarshalJSON(data); err != nil {
+			return nil, err
+		}
+		return msg, nil
+	}
+	return nil, errUnknownJSONPacket
+}
+
+// UnmarshalPacketYAML returns the message or bundle of the YAML form of
+// Message.MarshalYAML or Bundle.MarshalYAML. unmarshal decodes the YAML value,
+// e.g. the Decode method of a yaml.Node or the function passed to an
+// UnmarshalYAML method.
+func UnmarshalPacketYAML(unmarshal func(any) error) (Packet, error) {
+	var fields map[string]any
+	if err := unmarshal(&fields); err != nil {
+		return nil, err
+	}
+
+	if _, ok := fields["timetag"]; ok {
+		b := &Bundle{}
+		if err := b.UnmarshalYAML(unmarshal); err != nil {
+			return nil, err
+		}
+		return b, nil
+	}
+	if _, ok := fields["address"]; ok {
+		msg := &Message{}
+		if err := msg.UnmarshalYAML(unmarshal); err != nil {
+			return nil, err
+		}
+		return msg, nil
+	}
+	return nil, errUnknownJSONPacket
+}
+
+// MarshalJSON implements the json.Marshaler interface. The timetag is a
+// number.
+func (t Timetag) MarshalJSON() ([]byte, error) {
+	return strconv.AppendUint(nil, uint64(t), 10), nil
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface. Besides numbers,
+// it accepts the strings "immediate", "now" and RFC 3339 times.
+func (t *Timetag) UnmarshalJSON(data []byte) error {
+	var v any
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	if err := d.Decode(&v); err != nil {
+		return err
+	}
+	return t.fromJSON(v)
+}
+
+// MarshalYAML returns the timetag as number.
+func (t Timetag) MarshalYAML() (any, error) {
+	return uint64(t), nil
+}
+
+// UnmarshalYAML implements the Unmarshaler interface of the YAML packages.
+// Like UnmarshalJSON, it accepts numbers and strings.
+func (t *Timetag) UnmarshalYAML(unmarshal func(any) error) error {
+	var v any
+	if err := unmarshal(&v); err != nil {
+		return err
+	}
+	return t.fromJSON(v)
+}
+
+// fromJSON sets the timetag to the JSON or YAML value v.
+func (t *Timetag) fromJSON(v any) error {
+	arg, err := argumentFromJSON(jsonArgument{Type: "t", Value: v})
+	if err != nil {
+		return err
+	}
+	*t = arg.(Timetag)
+	return nil
+}
@@ -0,0 +1,173 @@
+package osc_test
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+
+	"bekuba.de/go-osc"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+// newJSONBundle returns a nested bundle with all argument types.
+func newJSONBundle() *osc.Bundle {
+	inner := osc.NewBundle(time.Unix(1, 0))
+	inner.Messages = append(inner.Messages, &osc.Message{Address: "/untyped", Untyped: true, RawArguments: []byte{0, 0, 0, 1}})
+	bundle := osc.NewBundle(time.Unix(2, 0))
+	bundle.Messages = append(bundle.Messages,
+		osc.NewMessage("/int", int32(0), int32(math.MinInt32), int64(math.MaxInt64), int64(1)),
+		osc.NewMessage("/float", float32(0.1), float32(math.Inf(-1)), float64(0.1), math.Inf(1)),
+		osc.NewMessage("/string", "", "text", []byte{}, []byte("text")),
+		osc.NewMessage("/values", true, false, nil, osc.NewImmediateTimetag(), osc.Timetag(math.MaxUint64)),
+		osc.NewMessage("/empty"),
+	)
+	bundle.Bundles = append(bundle.Bundles, inner, osc.NewBundle(time.Unix(3, 0)))
+	return bundle
+}
+
+func TestJSON(t *testing.T) {
+	bundle := newJSONBundle()
+
+	data, err := json.Marshal(bundle)
+	assert.NoError(t, err)
+
+	var parsed osc.Bundle
+	assert.NoError(t, json.Unmarshal(data, &parsed))
+	assert.Equal(t, bundle, &parsed)
+
+	packet, err := osc.UnmarshalPacketJSON(data)
+	assert.NoError(t, err)
+	assert.Equal(t, bundle, packet)
+
+	data, err = json.Marshal(osc.NewMessage("/a", int32(1), "s", true))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"address":"/a","arguments":[{"type":"i","value":1},{"type":"s","value":"s"},{"type":"T"}]}`, string(data))
+
+	packet, err = osc.UnmarshalPacketJSON(data)
+	assert.NoError(t, err)
+	assert.Equal(t, osc.NewMessage("/a", int32(1), "s", true), packet)
+
+	// NaN isn't equal to itself
+	data, err = json.Marshal(osc.NewMessage("/nan", float32(math.NaN())))
+	assert.NoError(t, err)
+	var msg osc.Message
+	assert.NoError(t, json.Unmarshal(data, &msg))
+	assert.True(t, math.IsNaN(float64(msg.Arguments[0].(float32))))
+}
+
+func TestUnmarshalJSON(t *testing.T) {
+	var msg osc.Message
+	assert.NoError(t, json.Unmarshal([]byte(`{"address":"/a","arguments":[
+		{"type":"h","value":9007199254740993},
+		{"type":"f","value":2},
+		{"type":"d","value":"-Inf"},
+		{"type":"b","value":"AQI="},
+		{"type":"t","value":"immediate"},
+		{"type":"t","value":"1970-01-01T00:00:01Z"},
+		{"type":"N","value":null}
+	]}`), &msg))
+	assert.Equal(t, osc.NewMessage("/a", int64(9007199254740993), float32(2), math.Inf(-1), []byte{1, 2},
+		osc.NewImmediateTimetag(), osc.NewTimetagFromTime(time.Unix(1, 0)), nil), &msg)
+
+	var timetag osc.Timetag
+	assert.NoError(t, json.Unmarshal([]byte(`"immediate"`), &timetag))
+	assert.Equal(t, osc.NewImmediateTimetag(), timetag)
+	assert.NoError(t, json.Unmarshal([]byte(`18446744073709551615`), &timetag))
+	assert.Equal(t, osc.Timetag(math.MaxUint64), timetag)
+
+	for _, arg := range []string{
+		`{"type":"i","value":2147483648}`,
+		`{"type":"i","value":1.5}`,
+		`{"type":"i","value":"1"}`,
+		`{"type":"i"}`,
+		`{"type":"s","value":1}`,
+		`{"type":"b","value":"!"}`,
+		`{"type":"t","value":-1}`,
+		`{"type":"t","value":"tomorrow"}`,
+		`{"type":"f","value":true}`,
+		`{"type":"f","value":"0.5"}`,
+		`{"type":"c","value":"a"}`,
+	} {
+		err := json.Unmarshal([]byte(`{"address":"/a","arguments":[`+arg+`]}`), &msg)
+		assert.ErrorIs(t, err, osc.ErrorInvalidArgument, arg)
+	}
+
+	assert.ErrorIs(t, json.Unmarshal([]byte(`{"address":"a"}`), &msg), osc.ErrorOscAddress)
+	assert.ErrorIs(t, json.Unmarshal([]byte(`{"address":"/a","untyped":true,"raw":"!"}`), &msg), osc.ErrorInvalidArgument)
+	assert.ErrorIs(t, json.Unmarshal([]byte(`{"timetag":1,"messages":[{"address":""}]}`), &osc.Bundle{}), osc.ErrorOscAddress)
+	_, err := osc.UnmarshalPacketJSON([]byte(`[]`))
+	assert.Error(t, err)
+	_, err = osc.UnmarshalPacketJSON([]byte(`{"adress":"/a"}`))
+	assert.ErrorIs(t, err, osc.ErrorInvalidPacked)
+	_, err = osc.UnmarshalPacketJSON([]byte(`{"timetag":1,"messages":[null]}`))
+	assert.ErrorIs(t, err, osc.ErrorInvalidArgument)
+	_, err = osc.UnmarshalPacketJSON([]byte(`{"timetag":1,"bundles":[null]}`))
+	assert.ErrorIs(t, err, osc.ErrorInvalidArgument)
+}
+
+func TestMarshalJSONValue(t *testing.T) {
+	// messages and bundles stored by value keep their type tags
+	v := struct {
+		Message osc.Message `json:"message" yaml:"message"`
+		Bundle  osc.Bundle  `json:"bundle" yaml:"bundle"`
+	}{Message: *osc.NewMessage("/a", int32(1)), Bundle: *newJSONBundle()}
+
+	data, err := json.Marshal(v)
+	assert.NoError(t, err)
+	parsed := v
+	parsed.Message, parsed.Bundle = osc.Message{}, osc.Bundle{}
+	assert.NoError(t, json.Unmarshal(data, &parsed))
+	assert.Equal(t, v, parsed)
+
+	data, err = yaml.Marshal(v)
+	assert.NoError(t, err)
+	parsed.Message, parsed.Bundle = osc.Message{}, osc.Bundle{}
+	assert.NoError(t, yaml.Unmarshal(data, &parsed))
+	assert.Equal(t, v, parsed)
+}
+
+func TestYAML(t *testing.T) {
+	bundle := newJSONBundle()
+
+	data, err := yaml.Marshal(bundle)
+	assert.NoError(t, err)
+
+	var parsed osc.Bundle
+	assert.NoError(t, yaml.Unmarshal(data, &parsed), string(data))
+	assert.Equal(t, bundle, &parsed, string(data))
+
+	assert.ErrorIs(t, yaml.Unmarshal([]byte("timetag: 1\nmessages: [null]\n"), &parsed), osc.ErrorInvalidArgument)
+	assert.ErrorIs(t, yaml.Unmarshal([]byte("timetag: 1\nbundles: [~]\n"), &parsed), osc.ErrorInvalidArgument)
+
+	// the packet of a YAML value
+	var node yaml.Node
+	assert.NoError(t, yaml.Unmarshal(data, &node))
+	packet, err := osc.UnmarshalPacketYAML(node.Decode)
+	assert.NoError(t, err)
+	assert.Equal(t, bundle, packet)
+
+	data, err = yaml.Marshal(bundle.Messages[0])
+	assert.NoError(t, err)
+	assert.NoError(t, yaml.Unmarshal(data, &node))
+	packet, err = osc.UnmarshalPacketYAML(node.Decode)
+	assert.NoError(t, err)
+	assert.Equal(t, bundle.Messages[0], packet)
+
+	assert.NoError(t, yaml.Unmarshal([]byte("adress: /a\n"), &node))
+	_, err = osc.UnmarshalPacketYAML(node.Decode)
+	assert.ErrorIs(t, err, osc.ErrorInvalidPacked)
+
+	var msg osc.Message
+	assert.NoError(t, yaml.Unmarshal([]byte(`
+address: /a
+arguments:
+  - {type: h, value: 9223372036854775807}
+  - {type: d, value: .inf}
+  - {type: t, value: immediate}
+  - {type: F}
+`), &msg))
+	assert.Equal(t, osc.NewMessage("/a", int64(math.MaxInt64), math.Inf(1), osc.NewImmediateTimetag(), false), &msg)
+}
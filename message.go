@@ -16,6 +16,12 @@ type ArgumentsType []any
 type Message struct {
 	Address   string
 	Arguments ArgumentsType
+
+	// Untyped is true for messages received without a type tag string from
+	// legacy OSC devices (see DecodeOptions.Lenient). The arguments of these
+	// messages can't be decoded, RawArguments holds the raw argument bytes.
+	Untyped      bool
+	RawArguments []byte
 }
 
 // Verify that Messages implements the Packet interface.
@@ -74,13 +80,19 @@ func (msg *Message) TypeTags() string {
 	return tags.String()
 }
 
-// String implements the fmt.Stringer interface.
+// String implements the fmt.Stringer interface. The format can be parsed by
+// ParseMessage.
 func (msg *Message) String() string {
 	if msg == nil {
 		return ""
 	}
 
 	var s strings.Builder
+	if msg.Untyped {
+		s.WriteString(fmt.Sprintf("%s untyped %d", msg.Address, msg.RawArguments))
+		return s.String()
+	}
+
 	tags := msg.TypeTags()
 	s.WriteString(fmt.Sprintf("%s %s", msg.Address, tags))
 
@@ -108,6 +120,9 @@ func (msg *Message) String() string {
 // 1. OSC Address Pattern
 // 2. OSC Type Tag String
 // 3. OSC Arguments.
+//
+// Untyped messages are serialized without type tag string, followed by the
+// padded RawArguments.
 func (msg *Message) MarshalBinary() ([]byte, error) {
 	// We can start with the OSC address and add it to the buffer
 	data := new(bytes.Buffer)
@@ -117,6 +132,16 @@ func (msg *Message) MarshalBinary() ([]byte, error) {
 		return nil, err
 	}
 
+	if msg.Untyped {
+		if _, err := data.Write(msg.RawArguments); err != nil {
+			return nil, err
+		}
+		if _, err := data.Write(make([]byte, padBytesNeeded(len(msg.RawArguments)))); err != nil {
+			return nil, err
+		}
+		return data.Bytes(), nil
+	}
+
 	// Type tag string starts with ","
 	lenArgs := len(msg.Arguments)
 	typetags := make([]byte, lenArgs+1)
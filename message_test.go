@@ -287,3 +287,26 @@ func TestEmptyString(t *testing.T) {
 
 	assert.Equal(t, "", msg.Arguments[0])
 }
+
+func TestUntypedMessage(t *testing.T) {
+	data := []byte{'/', 'o', 'l', 'd', 0, 0, 0, 0, 0, 0, 0, 42, 0x3f, 0x80, 0, 0}
+
+	p, err := osc.DecodeOptions{Lenient: true}.UnmarshalPacket(data)
+	assert.NoError(t, err)
+
+	msg, ok := p.(*osc.Message)
+	if assert.True(t, ok) {
+		assert.True(t, msg.Untyped)
+		assert.Equal(t, 0, len(msg.Arguments))
+		assert.Equal(t, data[8:], msg.RawArguments)
+		assert.Equal(t, "/old untyped [0 0 0 42 63 128 0 0]", msg.String())
+
+		d, err := msg.MarshalBinary()
+		assert.NoError(t, err)
+		assert.Equal(t, data, d)
+	}
+
+	// default decode options reject messages without type tag string
+	_, err = osc.UnmarshalPacket(data)
+	assert.ErrorIs(t, err, osc.ErrorMissingTypeTags)
+}
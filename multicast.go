@@ -0,0 +1,84 @@
+package osc
+
+import (
+	"fmt"
+	"net"
+)
+
+// MulticastOptions configures a multicast Node.
+type MulticastOptions struct {
+	// Interface is the network interface for joining the multicast group and
+	// sending multicast packets. Nil means the system default interface.
+	Interface *net.Interface
+
+	// TTL is the time-to-live (IPv6: hop limit) of sent multicast packets.
+	// Zero means 1, the packets don't leave the local network.
+	TTL int
+
+	// Loopback enables receiving of multicast packets sent by the local host,
+	// including the packets sent by the node itself.
+	Loopback bool
+}
+
+// NewMulticastNode returns a Node, that joins the multicast group gaddr (e.g.
+// "239.1.2.3:9000") and listens on the port of gaddr. OSC packets are sent to
+// all members of the group with SendTo(gaddr, packet).
+func NewMulticastNode(gaddr string, opts MulticastOptions) (*Node, error) {
+	addr, err := net.ResolveUDPAddr("udp", gaddr)
+	if err != nil || !addr.IP.IsMulticast() {
+		return nil, ErrorOscAddressFormat
+	}
+
+	network := "udp4"
+	ipv6 := addr.IP.To4() == nil
+	if ipv6 {
+		network = "udp6"
+	}
+
+	conn, err := net.ListenMulticastUDP(network, opts.Interface, addr)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrorOscAddress, err)
+	}
+
+	ttl := opts.TTL
+	if ttl <= 0 {
+		ttl = 1
+	}
+	if err := setMulticastTTL(conn, ipv6, ttl); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if err := setMulticastLoopback(conn, ipv6, opts.Loopback); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return &Node{conn: conn}, nil
+}
+
+// JoinGroup joins the multicast group with the IP address group (e.g.
+// "239.1.2.3") on the interface ifi. If ifi is nil, the system default
+// interface is used. The node receives the packets sent to the group and its
+// port.
+func (sc *Node) JoinGroup(group string, ifi *net.Interface) error {
+	return sc.setMembership(group, ifi, true)
+}
+
+// LeaveGroup leaves the multicast group with the IP address group on the
+// interface ifi.
+func (sc *Node) LeaveGroup(group string, ifi *net.Interface) error {
+	return sc.setMembership(group, ifi, false)
+}
+
+// setMembership joins or leaves a multicast group.
+func (sc *Node) setMembership(group string, ifi *net.Interface, join bool) error {
+	ip := net.ParseIP(group)
+	if ip == nil || !ip.IsMulticast() {
+		return ErrorOscAddressFormat
+	}
+	conn, err := sc.udpConn()
+	if err != nil {
+		return err
+	}
+	return setMembership(conn, ip, ifi, join)
+}
@@ -0,0 +1,111 @@
+package osc_test
+
+import (
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"bekuba.de/go-osc"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// freePort returns a free UDP port.
+func freePort(t *testing.T) int {
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	assert.NoError(t, err)
+	defer conn.Close()
+	return conn.LocalAddr().(*net.UDPAddr).Port
+}
+
+func TestMulticastNode(t *testing.T) {
+	port := freePort(t)
+	group := fmt.Sprintf("239.255.77.1:%d", port)
+	group2 := fmt.Sprintf("239.255.77.2:%d", port)
+
+	opts := osc.MulticastOptions{Loopback: true}
+	app1, err := osc.NewMulticastNode(group, opts)
+	if err != nil {
+		t.Skipf("multicast not available: %v", err)
+	}
+	defer app1.Close()
+
+	app2, err := osc.NewMulticastNode(group, opts)
+	assert.NoError(t, err)
+	defer app2.Close()
+
+	received := make(chan string, 10)
+	for i, app := range []*osc.Node{app1, app2} {
+		d := osc.NewStandardDispatcher()
+		err = d.AddMsgHandler("*", func(msg *osc.Message) {
+			received <- fmt.Sprintf("app%d: %v", i+1, msg)
+		})
+		assert.NoError(t, err)
+		go app.ListenAndServe(d)
+	}
+
+	expect := func(want ...string) {
+		var got []string
+		for range want {
+			select {
+			case s := <-received:
+				got = append(got, s)
+			case <-time.After(time.Second):
+				t.Fatalf("timeout, got %v, want %v", got, want)
+			}
+		}
+		assert.ElementsMatch(t, want, got)
+
+		select {
+		case s := <-received:
+			t.Errorf("unexpected %v", s)
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+
+	// all members of the group get the cue, app1 too (loopback)
+	err = app1.SendMsgTo(group, "/cue/go", 1)
+	if err != nil {
+		t.Skipf("multicast not available: %v", err)
+	}
+	expect("app1: /cue/go ,i 1", "app2: /cue/go ,i 1")
+
+	// app2 joins a second group
+	err = app2.JoinGroup("239.255.77.2", nil)
+	assert.NoError(t, err)
+	err = app1.SendMsgTo(group2, "/cue/go", 2)
+	assert.NoError(t, err)
+	expect("app1: /cue/go ,i 2", "app2: /cue/go ,i 2")
+
+	// app2 leaves the second group
+	err = app2.LeaveGroup("239.255.77.2", nil)
+	assert.NoError(t, err)
+	err = app1.SendMsgTo(group2, "/cue/go", 3)
+	assert.NoError(t, err)
+	expect()
+
+	// invalid groups
+	assert.ErrorIs(t, app1.JoinGroup("10.0.0.1", nil), osc.ErrorOscAddressFormat)
+	_, err = osc.NewMulticastNode(fmt.Sprintf("127.0.0.1:%d", port), opts)
+	assert.ErrorIs(t, err, osc.ErrorOscAddressFormat)
+}
+
+func TestMulticastLoopback(t *testing.T) {
+	group := fmt.Sprintf("239.255.77.3:%d", freePort(t))
+
+	app, err := osc.NewMulticastNode(group, osc.MulticastOptions{TTL: 2})
+	if err != nil {
+		t.Skipf("multicast not available: %v", err)
+	}
+	defer app.Close()
+
+	// without loopback the node doesn't receive its own packets
+	err = app.SendMsgTo(group, "/cue/go")
+	if err != nil {
+		t.Skipf("multicast not available: %v", err)
+	}
+	app.ReadTimeout = 100 * time.Millisecond
+	_, _, err = app.Read()
+	assert.Error(t, err)
+}
@@ -1,46 +1,176 @@
 package osc
 
 import (
-	"bufio"
-	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"math"
 	"net"
+	"os"
 	"sync"
+	"syscall"
 	"time"
 )
 
+const (
+	// DefaultMaxPacketSize is the maximum size of an OSC packet, if
+	// Node.MaxPacketSize is not set.
+	DefaultMaxPacketSize = 65535
+
+	// EthernetMaxPacketSize is the largest UDP payload that fits into one
+	// Ethernet frame (MTU 1500) without IP fragmentation.
+	EthernetMaxPacketSize = 1472
+)
+
+// Networks for NewNodeNetwork
+const (
+	// NetworkUDP is IPv4 or IPv6, depending on the local address. A node with
+	// an unspecified local address (e.g. ":8000") receives IPv4 and IPv6
+	// packets on most platforms.
+	NetworkUDP = "udp"
+	// NetworkUDP4 is IPv4 only.
+	NetworkUDP4 = "udp4"
+	// NetworkUDP6 is IPv6 only.
+	NetworkUDP6 = "udp6"
+	// NetworkDualStack is an IPv6 socket, that receives IPv4 packets too. IPv4
+	// peers have IPv4 addresses in handlers (not IPv4-mapped IPv6 addresses).
+	NetworkDualStack = "dual"
+	// NetworkUnixgram is a Unix domain datagram socket. The addresses are
+	// file system paths, or names in the abstract namespace starting with
+	// '@' (Linux only).
+	NetworkUnixgram = "unixgram"
+)
+
 // Node structure
 type Node struct {
-	conn *net.UDPConn
+	conn net.PacketConn
 	//	Dispatcher  Dispatcher
 	ReadTimeout time.Duration
+	// MaxPacketSize is the maximum size of sent and received OSC packets in
+	// bytes. Zero means DefaultMaxPacketSize.
+	MaxPacketSize int
+	// DecodeOptions controls how received OSC packets are decoded.
+	DecodeOptions DecodeOptions
+	// RequestTimeout is the time Request waits for a reply, before the
+	// request is sent again. Zero means Request waits until its context is
+	// done.
+	RequestTimeout time.Duration
+	// RequestRetries is the number of times Request sends a request again.
+	RequestRetries int
+	// ReliableTimeout is the time SendReliable waits for an acknowledgement,
+	// before the packet is sent again. It is doubled for every retransmission
+	// up to ReliableMaxTimeout. Zero means DefaultReliableTimeout.
+	ReliableTimeout time.Duration
+	// ReliableMaxTimeout is the maximum time SendReliable waits for an
+	// acknowledgement. Zero means DefaultReliableMaxTimeout.
+	ReliableMaxTimeout time.Duration
+	// ReliableRetries is the number of times SendReliable sends a packet
+	// again. Zero means DefaultReliableRetries, negative means never.
+	ReliableRetries int
+	// AcceptReliable acknowledges the packets of SendReliable, drops
+	// duplicates and dispatches their payload, if it is set. Otherwise they
+	// are dispatched like other bundles. The acknowledgements of the own
+	// SendReliable are received in any case.
+	AcceptReliable bool
+	// Peers records all remote addresses, that send packets to the node, if
+	// it is set.
+	Peers *PeerRegistry
+	// Auth signs all sent packets and verifies all received packets, if it
+	// is set. Packets, that fail verification, are dropped.
+	Auth *Authenticator
+	// DecodeFailed is called by ListenAndServe for every dropped packet, that
+	// is too large, fails verification or can't be decoded.
+	DecodeFailed func(addr net.Addr, err error)
+
+	network string
+	// accept filters the sources of received packets, if it is set
+	accept   func(addr net.Addr) bool
+	requests requestTable
+	reliable reliableState
+	watchers watchers
 }
 
 // Node create a new OSC Server and/or Client connection
 func NewNode(laddr string) (*Node, error) {
+	return NewNodeNetwork(NetworkUDP, laddr)
+}
 
-	addr, err := net.ResolveUDPAddr("udp", laddr)
-	if err != nil {
-		return nil, ErrorOscAddressFormat
-	}
-	conn, err := net.ListenUDP("udp", addr)
-	if err != nil {
-		return nil, ErrorOscAddress
+// NewNodeNetwork creates a new OSC Server and/or Client connection for the
+// network NetworkUDP, NetworkUDP4, NetworkUDP6, NetworkDualStack or
+// NetworkUnixgram. laddr may contain an IPv6 zone, e.g. "[fe80::1%eth0]:8000".
+// The socket file of a NetworkUnixgram node is removed by Close.
+func NewNodeNetwork(network, laddr string) (*Node, error) {
+	var conn net.PacketConn
+
+	switch network {
+	case NetworkUDP, NetworkUDP4, NetworkUDP6:
+		addr, err := net.ResolveUDPAddr(network, laddr)
+		if err != nil {
+			return nil, ErrorOscAddressFormat
+		}
+		conn, err = net.ListenUDP(network, addr)
+		if err != nil {
+			return nil, ErrorOscAddress
+		}
+
+	case NetworkDualStack:
+		if _, err := net.ResolveUDPAddr(NetworkUDP6, laddr); err != nil {
+			return nil, ErrorOscAddressFormat
+		}
+		lc := net.ListenConfig{
+			Control: func(network, address string, c syscall.RawConn) error {
+				return setDualStack(c)
+			},
+		}
+		var err error
+		conn, err = lc.ListenPacket(context.Background(), NetworkUDP6, laddr)
+		if err != nil {
+			return nil, ErrorOscAddress
+		}
+
+	case NetworkUnixgram:
+		addr, err := net.ResolveUnixAddr(network, laddr)
+		if err != nil {
+			return nil, ErrorOscAddressFormat
+		}
+		conn, err = net.ListenUnixgram(network, addr)
+		if err != nil {
+			return nil, ErrorOscAddress
+		}
+
+	default:
+		return nil, fmt.Errorf("%w: unknown network %q", ErrorOscAddressFormat, network)
 	}
 
-	return &Node{conn: conn}, nil
+	return &Node{conn: conn, network: network}, nil
+}
+
+// NewNodeFromConn creates a new OSC Server and/or Client connection with an
+// existing connection, e.g. a custom transport. The addresses of SendTo are
+// resolved as UDP addresses, unless conn is a Unix domain socket.
+func NewNodeFromConn(conn net.PacketConn) *Node {
+	network := NetworkUDP
+	if _, ok := conn.LocalAddr().(*net.UnixAddr); ok {
+		network = NetworkUnixgram
+	}
+	return &Node{conn: conn, network: network}
 }
 
 // SendTo sends an OSC Bundle or an OSC Message (as OSC Client) to a given UDP address.
 func (sc *Node) SendToUDPAddr(raddr *net.UDPAddr, packet Packet) (err error) {
+	return sc.SendToAddr(raddr, packet)
+}
+
+// SendToAddr sends an OSC Bundle or an OSC Message (as OSC Client) to a given
+// address of the network of the node (e.g. *net.UnixAddr).
+func (sc *Node) SendToAddr(raddr net.Addr, packet Packet) (err error) {
 	if sc.conn != nil {
 
 		data, err := packet.MarshalBinary()
 		if err != nil {
 			return err
 		}
-		if _, err = sc.conn.WriteTo(data, raddr); err != nil {
+		if err = sc.writeTo(data, raddr); err != nil {
 			return err
 		}
 	} else {
@@ -51,17 +181,33 @@ func (sc *Node) SendToUDPAddr(raddr *net.UDPAddr, packet Packet) (err error) {
 
 // SendTo sends an OSC Bundle or an OSC Message (as OSC Client) to a given address.
 func (sc *Node) SendTo(raddr string, packet Packet) (err error) {
-	addr, err := net.ResolveUDPAddr("udp", raddr)
+	addr, err := sc.resolve(raddr)
 	if err != nil {
-		return ErrorOscAddressFormat
+		return err
 	}
-	return sc.SendToUDPAddr(addr, packet)
+	return sc.SendToAddr(addr, packet)
 }
 
 // SendMsgTo sends a OSC Message to a given UDP address(all int types converted to int32)
 // Default int is int32, include int values in range of int32
 // If you need a int value in range of int64 convert the arg to int64
 func (sc *Node) SendMsgToUDPAddr(addr *net.UDPAddr, path string, args ...any) error {
+	return sc.SendMsgToAddr(addr, path, args...)
+}
+
+// SendMsgToAddr sends a OSC Message to a given address of the network of the
+// node (all int types converted to int32, see SendMsgTo).
+func (sc *Node) SendMsgToAddr(addr net.Addr, path string, args ...any) error {
+	msg, err := newMessageFromArgs(path, args...)
+	if err != nil {
+		return err
+	}
+	return sc.SendToAddr(addr, msg)
+}
+
+// newMessageFromArgs returns a new OSC Message (all int types converted to
+// int32, see SendMsgTo).
+func newMessageFromArgs(path string, args ...any) (*Message, error) {
 	var a []any
 
 	for _, arg := range args {
@@ -78,31 +224,33 @@ func (sc *Node) SendMsgToUDPAddr(addr *net.UDPAddr, path string, args ...any) er
 			if (t <= math.MaxInt32) && (t >= math.MinInt32) {
 				a = append(a, int32(t))
 			} else {
-				return fmt.Errorf("int32 %d out of range", t)
+				return nil, fmt.Errorf("int32 %d out of range", t)
 			}
 		case bool, int64, int32, float32, float64, string, nil, []byte, Timetag:
 			a = append(a, t)
 		default:
-			return fmt.Errorf("wrong datatype, can't send OSC packet")
+			return nil, fmt.Errorf("wrong datatype, can't send OSC packet")
 		}
 
 	}
 
-	return sc.SendToUDPAddr(addr, NewMessage(path, a...))
+	return NewMessage(path, a...), nil
 }
 
 // SendMsgTo sends a OSC Message to a given address(all int types converted to int32)
 // Default int is int32, include int values in range of int32
 // If you need a int value in range of int64 convert the arg to int64
 func (sc *Node) SendMsgTo(raddr string, path string, args ...any) error {
-	addr, err := net.ResolveUDPAddr("udp", raddr)
+	addr, err := sc.resolve(raddr)
 	if err != nil {
-		return ErrorOscAddressFormat
+		return err
 	}
-	return sc.SendMsgToUDPAddr(addr, path, args...)
+	return sc.SendMsgToAddr(addr, path, args...)
 }
 
-// ListenAndServe listen and serve as an OSC Server
+// ListenAndServe listen and serve as an OSC Server. Packets, that are too
+// large, fail verification or can't be decoded, are dropped and reported to
+// DecodeFailed. Other read errors stop serving and are returned.
 func (sc *Node) ListenAndServe(d Dispatcher) error {
 	if sc.conn != nil {
 
@@ -131,6 +279,14 @@ func (sc *Node) serve(c net.PacketConn, d Dispatcher) error {
 			return nil
 		}
 		msg, raddr, err := sc.Read()
+		var decodeErr *DecodeError
+		if errors.Is(err, ErrorPacketTooLarge) || errors.Is(err, ErrorAuthentication) || errors.As(err, &decodeErr) {
+			// drop the packet but keep on serving
+			if sc.DecodeFailed != nil {
+				sc.DecodeFailed(raddr, err)
+			}
+			continue
+		}
 		if err != nil {
 			ne, ok := err.(net.Error)
 
@@ -141,6 +297,11 @@ func (sc *Node) serve(c net.PacketConn, d Dispatcher) error {
 
 			return err
 		}
+		if msg = sc.receiveReliable(msg, raddr); msg == nil {
+			continue
+		}
+		sc.requests.deliver(msg, raddr)
+		sc.watchers.notify(msg, raddr)
 		if d != nil {
 			errChan := make(chan error)
 			go func() {
@@ -153,37 +314,207 @@ func (sc *Node) serve(c net.PacketConn, d Dispatcher) error {
 	}
 }
 
-// Read retrieves OSC packets.
+// Read retrieves OSC packets. The address of a NetworkUnixgram peer is nil, if
+// the peer socket isn't bound to an address. After Close, ErrorNodeClosed is
+// returned.
 func (s *Node) Read() (Packet, net.Addr, error) {
+	conn := s.conn
+	if conn == nil {
+		return nil, nil, ErrorNodeClosed
+	}
 	if s.ReadTimeout != 0 {
-		err := s.conn.SetReadDeadline(time.Now().Add(s.ReadTimeout))
+		err := conn.SetReadDeadline(time.Now().Add(s.ReadTimeout))
 		if err != nil {
 			return nil, nil, err
 		}
 	}
 
-	data := make([]byte, 65535)
+	// one byte more than allowed to detect too large packets
+	data := make([]byte, s.maxPacketSize()+1)
 
-	n, addr, err := s.conn.ReadFrom(data)
-	if err != nil {
-		return nil, nil, err
+	var n int
+	var addr net.Addr
+	var err error
+	for {
+		n, addr, err = conn.ReadFrom(data)
+		if err != nil {
+			return nil, nil, err
+		}
+		addr = unmapAddr(addr)
+		// packets of other sources are dropped silently
+		if s.accept == nil || s.accept(addr) {
+			break
+		}
+	}
+
+	var p Packet
+	data = data[:n]
+	if n > s.maxPacketSize() {
+		err = ErrorPacketTooLarge
+	} else if s.Auth != nil {
+		data, err = s.Auth.verify(data, addr)
+	}
+	if err == nil {
+		p, err = s.DecodeOptions.UnmarshalPacket(data)
 	}
 
-	var start int
-	p, err := readPacket(bufio.NewReader(bytes.NewBuffer(data)), &start, n)
+	if s.Peers != nil {
+		s.Peers.record(addr, n, err != nil)
+	}
 
 	return p, addr, err
 }
 
+// writeTo writes a marshaled OSC packet to addr.
+func (sc *Node) writeTo(data []byte, addr net.Addr) error {
+	conn := sc.conn
+	if conn == nil {
+		return ErrorNodeClosed
+	}
+	if sc.Auth != nil {
+		var err error
+		if data, err = sc.Auth.sign(data, addr); err != nil {
+			return err
+		}
+	}
+	if len(data) > sc.maxPacketSize() {
+		return fmt.Errorf("%w: %d bytes (max. %d bytes)", ErrorPacketTooLarge, len(data), sc.maxPacketSize())
+	}
+	_, err := conn.WriteTo(data, addr)
+	return err
+}
+
+// watchers holds functions, that are called for every received packet. The
+// zero value has no watchers.
+type watchers struct {
+	mutex  sync.Mutex
+	nextID uint64
+	funcs  map[uint64]func(packet Packet, addr net.Addr)
+}
+
+// add adds the watcher f and returns a function to remove it.
+func (w *watchers) add(f func(packet Packet, addr net.Addr)) (remove func()) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.funcs == nil {
+		w.funcs = make(map[uint64]func(packet Packet, addr net.Addr))
+	}
+	w.nextID++
+	id := w.nextID
+	w.funcs[id] = f
+
+	return func() {
+		w.mutex.Lock()
+		defer w.mutex.Unlock()
+		delete(w.funcs, id)
+	}
+}
+
+// notify calls all watchers with a received packet.
+func (w *watchers) notify(packet Packet, addr net.Addr) {
+	w.mutex.Lock()
+	funcs := make([]func(packet Packet, addr net.Addr), 0, len(w.funcs))
+	for _, f := range w.funcs {
+		funcs = append(funcs, f)
+	}
+	w.mutex.Unlock()
+
+	for _, f := range funcs {
+		f(packet, addr)
+	}
+}
+
+// resolve returns the address of raddr for the network of the node.
+func (sc *Node) resolve(raddr string) (net.Addr, error) {
+	network := NetworkUDP
+	switch sc.network {
+	case NetworkUDP4, NetworkUDP6:
+		network = sc.network
+	case NetworkUnixgram:
+		addr, err := net.ResolveUnixAddr(sc.network, raddr)
+		if err != nil {
+			return nil, ErrorOscAddressFormat
+		}
+		return addr, nil
+	}
+
+	addr, err := net.ResolveUDPAddr(network, raddr)
+	if err != nil {
+		return nil, ErrorOscAddressFormat
+	}
+	return addr, nil
+}
+
+// unmapAddr converts an IPv4-mapped IPv6 address (e.g. received by a dual
+// stack node) to an IPv4 address.
+func unmapAddr(addr net.Addr) net.Addr {
+	if udpAddr, ok := addr.(*net.UDPAddr); ok && len(udpAddr.IP) == net.IPv6len {
+		if ip4 := udpAddr.IP.To4(); ip4 != nil {
+			return &net.UDPAddr{IP: ip4, Port: udpAddr.Port}
+		}
+	}
+	return addr
+}
+
+// maxPacketSize returns the maximum packet size of the node.
+func (sc *Node) maxPacketSize() int {
+	if sc.MaxPacketSize > 0 {
+		return sc.MaxPacketSize
+	}
+	return DefaultMaxPacketSize
+}
+
+// Close closes the connection of the node. Closing a closed node does nothing.
 func (sc *Node) Close() {
 	done := sync.WaitGroup{}
 	done.Add(1)
 	c := sc.conn
 	sc.conn = nil
 	done.Done()
+	if c == nil {
+		return
+	}
 	c.Close()
+
+	// the socket file of a Unix domain socket isn't removed by Close
+	if addr, ok := c.LocalAddr().(*net.UnixAddr); ok && addr.Name != "" && addr.Name[0] != '@' {
+		os.Remove(addr.Name)
+	}
+}
+
+// SetBroadcast enables or disables sending to broadcast addresses (the
+// SO_BROADCAST socket option). DestinationGroup enables it for broadcast
+// addresses.
+func (sc *Node) SetBroadcast(enable bool) error {
+	conn, err := sc.udpConn()
+	if err != nil {
+		return err
+	}
+	return setBroadcast(conn, enable)
 }
 
+// Conn returns the UDP connection of the node or nil, if the node isn't a UDP
+// node.
 func (sc *Node) Conn() *net.UDPConn {
+	conn, _ := sc.conn.(*net.UDPConn)
+	return conn
+}
+
+// PacketConn returns the connection of the node.
+func (sc *Node) PacketConn() net.PacketConn {
 	return sc.conn
 }
+
+// udpConn returns the UDP connection of the node, ErrorNodeClosed or
+// ErrorNotSupported.
+func (sc *Node) udpConn() (*net.UDPConn, error) {
+	if sc.conn == nil {
+		return nil, ErrorNodeClosed
+	}
+	conn, ok := sc.conn.(*net.UDPConn)
+	if !ok {
+		return nil, ErrorNotSupported
+	}
+	return conn, nil
+}
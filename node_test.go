@@ -1,6 +1,7 @@
 package osc_test
 
 import (
+	"fmt"
 	"net"
 	"sync"
 	"testing"
@@ -263,3 +264,221 @@ func TestReadTimeout(t *testing.T) {
 	wait.Wait()
 	assert.Equal(t, false, get)
 }
+
+func TestMaxPacketSize(t *testing.T) {
+	app1, err := osc.NewNode("127.0.0.1:0")
+	assert.NoError(t, err)
+	defer app1.Close()
+	addr1 := app1.Conn().LocalAddr().String()
+	app1.MaxPacketSize = 64
+
+	app2, err := osc.NewNode("127.0.0.1:0")
+	assert.NoError(t, err)
+	defer app2.Close()
+	app2.MaxPacketSize = 64
+
+	// send
+	err = app2.SendTo(addr1, osc.NewMessage("/blob", make([]byte, 64)))
+	assert.ErrorIs(t, err, osc.ErrorPacketTooLarge)
+
+	err = app2.SendTo(addr1, osc.NewMessage("/blob", make([]byte, 32)))
+	assert.NoError(t, err)
+
+	// receive
+	app1.ReadTimeout = time.Second
+	p, _, err := app1.Read()
+	assert.NoError(t, err)
+	assert.Equal(t, osc.NewMessage("/blob", make([]byte, 32)), p)
+
+	app2.MaxPacketSize = 0
+	err = app2.SendTo(addr1, osc.NewMessage("/blob", make([]byte, 64)))
+	assert.NoError(t, err)
+
+	_, _, err = app1.Read()
+	assert.ErrorIs(t, err, osc.ErrorPacketTooLarge)
+}
+
+func TestDecodeFailed(t *testing.T) {
+	failed := make(chan error, 10)
+	d, received := newMessageReceiver(t)
+	server := newServingNode(t, d, func(node *osc.Node) {
+		node.MaxPacketSize = 64
+		node.DecodeFailed = func(addr net.Addr, err error) { failed <- err }
+	})
+
+	conn, err := net.Dial("udp", server.Conn().LocalAddr().String())
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("/a\x00\x00,x\x00\x00"))
+	assert.NoError(t, err)
+	_, err = conn.Write(make([]byte, 65))
+	assert.NoError(t, err)
+	data, err := osc.NewMessage(ping).MarshalBinary()
+	assert.NoError(t, err)
+	_, err = conn.Write(data)
+	assert.NoError(t, err)
+
+	// malformed packets are reported, serving goes on
+	for _, target := range []error{osc.ErrorInvalidPacked, osc.ErrorPacketTooLarge} {
+		select {
+		case err := <-failed:
+			assert.ErrorIs(t, err, target)
+		case <-time.After(time.Second):
+			t.Fatal("timeout")
+		}
+	}
+	select {
+	case msg := <-received:
+		assert.Equal(t, osc.NewMessage(ping), msg)
+	case <-time.After(time.Second):
+		t.Fatal("timeout")
+	}
+}
+
+func TestNodeClosed(t *testing.T) {
+	node, err := osc.NewNode("127.0.0.1:0")
+	assert.NoError(t, err)
+	node.Close()
+
+	// a closed node can be closed again, serving and reading return
+	node.Close()
+	_, _, err = node.Read()
+	assert.ErrorIs(t, err, osc.ErrorNodeClosed)
+	assert.Error(t, node.ListenAndServe(nil))
+	assert.Error(t, node.SendMsgTo("127.0.0.1:9", ping))
+}
+
+func TestIPv6(t *testing.T) {
+	app1, err := osc.NewNodeNetwork(osc.NetworkUDP6, "[::1]:0")
+	if err != nil {
+		t.Skipf("IPv6 not available: %v", err)
+	}
+	defer app1.Close()
+	addr1 := app1.Conn().LocalAddr().String()
+
+	app2, err := osc.NewNodeNetwork(osc.NetworkUDP6, "[::1]:0")
+	assert.NoError(t, err)
+	defer app2.Close()
+
+	err = app2.SendMsgTo(addr1, ping, int32(1))
+	assert.NoError(t, err)
+
+	app1.ReadTimeout = time.Second
+	p, addr, err := app1.Read()
+	assert.NoError(t, err)
+	assert.Equal(t, osc.NewMessage(ping, int32(1)), p)
+	assert.Equal(t, app2.Conn().LocalAddr().String(), addr.String())
+
+	// an IPv6 node can't send to IPv4 addresses
+	err = app2.SendMsgTo("127.0.0.1:8000", ping)
+	assert.Error(t, err)
+}
+
+func TestDualStack(t *testing.T) {
+	server, err := osc.NewNodeNetwork(osc.NetworkDualStack, "[::]:0")
+	if err != nil {
+		t.Skipf("dual stack not available: %v", err)
+	}
+	defer server.Close()
+	port := server.Conn().LocalAddr().(*net.UDPAddr).Port
+
+	addrs := make(chan net.Addr, 2)
+	d := osc.NewStandardDispatcher()
+	err = d.AddMsgHandlerExt(ping, func(msg *osc.Message, addr net.Addr) {
+		addrs <- addr
+	})
+	assert.NoError(t, err)
+	go server.ListenAndServe(d)
+
+	for _, tt := range []struct {
+		network string
+		laddr   string
+		ip      net.IP
+	}{
+		{osc.NetworkUDP4, "127.0.0.1:0", net.IPv4(127, 0, 0, 1).To4()},
+		{osc.NetworkUDP6, "[::1]:0", net.IPv6loopback},
+	} {
+		t.Run(tt.network, func(t *testing.T) {
+			client, err := osc.NewNodeNetwork(tt.network, tt.laddr)
+			if err != nil {
+				t.Skipf("%s not available: %v", tt.network, err)
+			}
+			defer client.Close()
+
+			err = client.SendMsgTo(net.JoinHostPort(tt.ip.String(), fmt.Sprint(port)), ping)
+			assert.NoError(t, err)
+
+			select {
+			case addr := <-addrs:
+				udpAddr := addr.(*net.UDPAddr)
+				assert.Equal(t, tt.ip, udpAddr.IP)
+				assert.Equal(t, client.Conn().LocalAddr().String(), addr.String())
+
+				// reply to the peer
+				err = server.SendMsgTo(addr.String(), pong)
+				assert.NoError(t, err)
+				client.ReadTimeout = time.Second
+				p, _, err := client.Read()
+				assert.NoError(t, err)
+				assert.Equal(t, osc.NewMessage(pong), p)
+			case <-time.After(time.Second):
+				t.Fatal("timeout")
+			}
+		})
+	}
+}
+
+func TestNodeNetwork(t *testing.T) {
+	_, err := osc.NewNodeNetwork("tcp", "127.0.0.1:0")
+	assert.ErrorIs(t, err, osc.ErrorOscAddressFormat)
+
+	_, err = osc.NewNodeNetwork(osc.NetworkDualStack, "127.0.0.1:0")
+	assert.ErrorIs(t, err, osc.ErrorOscAddressFormat)
+
+	app, err := osc.NewNodeNetwork(osc.NetworkUDP4, "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer app.Close()
+
+	// an IPv4 node can't send to IPv6 addresses
+	err = app.SendMsgTo("[::1]:8000", ping)
+	assert.ErrorIs(t, err, osc.ErrorOscAddressFormat)
+}
+
+func TestLinkLocal(t *testing.T) {
+	// find an interface with an IPv6 link-local address
+	var laddr string
+	ifis, _ := net.Interfaces()
+	for _, ifi := range ifis {
+		addrs, _ := ifi.Addrs()
+		for _, a := range addrs {
+			if ipnet, ok := a.(*net.IPNet); ok && ipnet.IP.To4() == nil && ipnet.IP.IsLinkLocalUnicast() {
+				laddr = net.JoinHostPort(ipnet.IP.String()+"%"+ifi.Name, "0")
+			}
+		}
+	}
+	if laddr == "" {
+		t.Skip("no IPv6 link-local address")
+	}
+
+	app1, err := osc.NewNodeNetwork(osc.NetworkUDP6, laddr)
+	if err != nil {
+		t.Skipf("link-local address not available: %v", err)
+	}
+	defer app1.Close()
+	addr1 := app1.Conn().LocalAddr().(*net.UDPAddr)
+	assert.NotEmpty(t, addr1.Zone)
+
+	app2, err := osc.NewNodeNetwork(osc.NetworkUDP6, laddr)
+	assert.NoError(t, err)
+	defer app2.Close()
+
+	err = app2.SendMsgTo(addr1.String(), ping)
+	assert.NoError(t, err)
+
+	app1.ReadTimeout = time.Second
+	p, addr, err := app1.Read()
+	assert.NoError(t, err)
+	assert.Equal(t, osc.NewMessage(ping), p)
+	assert.Equal(t, addr1.Zone, addr.(*net.UDPAddr).Zone)
+}
@@ -3,6 +3,7 @@ package osc
 import (
 	"bufio"
 	"bytes"
+	"io"
 )
 
 // helper for test of private functions
@@ -17,19 +18,29 @@ func WritePaddedString(str string, buf *bytes.Buffer) (int, error) {
 }
 
 func ReadPaddedString(reader *bufio.Reader) (string, int, error) {
-	return readPaddedString(reader)
+	data, _ := io.ReadAll(reader)
+	return readPaddedString(data)
 }
 
 func ReadBlob(reader *bufio.Reader) ([]byte, int, error) {
-	return readBlob(reader)
+	data, _ := io.ReadAll(reader)
+	return readBlob(data)
 }
 
 func ReadPacket(reader *bufio.Reader, start *int, end int) (Packet, error) {
-	return readPacket(reader, start, end)
+	data, _ := io.ReadAll(io.LimitReader(reader, int64(end-*start)))
+	d := &decoder{data: data}
+	p, err := d.readPacket(len(data))
+	*start += d.offset
+	return p, err
 }
 
 func ReadBundle(reader *bufio.Reader, start *int, end int) (*Bundle, error) {
-	return readBundle(reader, start, end)
+	data, _ := io.ReadAll(io.LimitReader(reader, int64(end-*start)))
+	d := &decoder{data: data}
+	b, err := d.readBundle(len(data))
+	*start += d.offset
+	return b, err
 }
 
 func (msg *Message) Match(addr string) (bool, error) {
@@ -1,14 +1,23 @@
 package osc
 
 import (
-	"bufio"
 	"encoding/binary"
-	"errors"
 	"fmt"
+	"io"
+	"math"
+	"strings"
 )
 
 const (
 	bundleTagString = "#bundle"
+
+	// DefaultMaxBundleDepth is the maximum nesting depth of bundles, if
+	// DecodeOptions.MaxBundleDepth is not set.
+	DefaultMaxBundleDepth = 16
+
+	// DefaultMaxArguments is the maximum number of arguments of a message, if
+	// DecodeOptions.MaxArguments is not set.
+	DefaultMaxArguments = 4096
 )
 
 // Packet is the interface for Message and Bundle.
@@ -16,71 +25,220 @@ type Packet interface {
 	MarshalBinary() (data []byte, err error)
 }
 
-// receivePacket receives an OSC packet from the given reader.
-func readPacket(reader *bufio.Reader, start *int, end int) (Packet, error) {
-	// var buf []byte
-	buf, err := reader.Peek(1)
+// DecodeOptions controls how received OSC packets are decoded. The zero
+// value decodes packets like the OSC 1.0 specification requires, but
+// tolerates some common deviations (e.g. trailing bytes).
+type DecodeOptions struct {
+	// Strict rejects all packets that don't follow the OSC 1.0 specification
+	// exactly: padding bytes must be zero, all sizes must be a multiple of 4,
+	// the packet must not contain trailing bytes, a message must have a type
+	// tag string and a valid address.
+	Strict bool
+
+	// Lenient accepts messages without a type tag string, as sent by older
+	// OSC 1.0 implementations. These messages are marked as Untyped and
+	// carry their raw argument bytes in RawArguments. It is off by default,
+	// because any corrupt packet with a valid address would be accepted as
+	// an untyped message. Enable it only for nodes talking to legacy devices.
+	Lenient bool
+
+	// MaxBundleDepth limits the nesting depth of bundles. Zero means
+	// DefaultMaxBundleDepth.
+	MaxBundleDepth int
+
+	// MaxArguments limits the number of arguments of a message. Zero means
+	// DefaultMaxArguments.
+	MaxArguments int
+
+	// MaxElementSize limits the size of bundle elements and blobs in bytes.
+	// Zero means DefaultMaxPacketSize.
+	MaxElementSize int
+}
+
+// UnmarshalPacket decodes an OSC Bundle or an OSC Message from data with the
+// default decode options.
+func UnmarshalPacket(data []byte) (Packet, error) {
+	return DecodeOptions{}.UnmarshalPacket(data)
+}
+
+// UnmarshalPacket decodes an OSC Bundle or an OSC Message from data. If data
+// isn't a valid OSC packet a *DecodeError is returned.
+func (opts DecodeOptions) UnmarshalPacket(data []byte) (Packet, error) {
+	d := &decoder{data: data, opts: opts}
+
+	p, err := d.readPacket(len(data))
 	if err != nil {
 		return nil, err
 	}
 
-	switch buf[0] {
+	if opts.Strict && d.offset != len(data) {
+		return nil, d.error("packet", ErrorTrailingBytes)
+	}
+
+	return p, nil
+}
+
+// maxBundleDepth returns the maximum nesting depth of bundles.
+func (opts DecodeOptions) maxBundleDepth() int {
+	if opts.MaxBundleDepth > 0 {
+		return opts.MaxBundleDepth
+	}
+	return DefaultMaxBundleDepth
+}
+
+// maxArguments returns the maximum number of arguments of a message.
+func (opts DecodeOptions) maxArguments() int {
+	if opts.MaxArguments > 0 {
+		return opts.MaxArguments
+	}
+	return DefaultMaxArguments
+}
+
+// maxElementSize returns the maximum size of bundle elements and blobs.
+func (opts DecodeOptions) maxElementSize() int {
+	if opts.MaxElementSize > 0 {
+		return opts.MaxElementSize
+	}
+	return DefaultMaxPacketSize
+}
+
+// decoder decodes OSC packets from data. offset is the position of the next
+// byte to read and depth the nesting depth of the current bundle.
+type decoder struct {
+	data   []byte
+	offset int
+	opts   DecodeOptions
+	depth  int
+}
+
+// error returns a DecodeError for the current offset.
+func (d *decoder) error(context string, err error) error {
+	return &DecodeError{Offset: d.offset, Context: context, Err: err}
+}
+
+// read returns the next n bytes before end.
+func (d *decoder) read(n int, context string, end int) ([]byte, error) {
+	if end-d.offset < n {
+		return nil, d.error(context, io.ErrUnexpectedEOF)
+	}
+	b := d.data[d.offset : d.offset+n]
+	d.offset += n
+	return b, nil
+}
+
+// readString reads a padded OSC string.
+func (d *decoder) readString(context string, end int) (string, error) {
+	s, n, err := readPaddedString(d.data[d.offset:end])
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return "", d.error(context, err)
+	}
+
+	if d.opts.Strict {
+		if err := checkPadding(d.data[d.offset+len(s) : d.offset+n]); err != nil {
+			return "", d.error(context, err)
+		}
+	}
+
+	d.offset += n
+	return s, nil
+}
+
+// readPacket reads an OSC Message or an OSC Bundle, that ends at end.
+func (d *decoder) readPacket(end int) (Packet, error) {
+	if d.offset >= end {
+		return nil, d.error("packet", io.ErrUnexpectedEOF)
+	}
+
+	if d.opts.Strict && (end-d.offset)%4 != 0 {
+		return nil, d.error("packet", ErrorInvalidAlignment)
+	}
+
+	switch d.data[d.offset] {
 	case '/':
-		return readMessage(reader, start)
+		return d.readMessage(end)
 
 	case '#':
-		return readBundle(reader, start, end)
+		return d.readBundle(end)
 	}
 
-	return nil, ErrorInvalidPacked
+	return nil, d.error("packet", ErrorInvalidPacked)
 }
 
-// readBundle reads an Bundle from reader.
-func readBundle(reader *bufio.Reader, start *int, end int) (*Bundle, error) {
+// readBundle reads an OSC Bundle.
+func (d *decoder) readBundle(end int) (*Bundle, error) {
+	if d.depth >= d.opts.maxBundleDepth() {
+		return nil, d.error("bundle", fmt.Errorf("%w: bundle depth > %d", ErrorLimitExceeded, d.opts.maxBundleDepth()))
+	}
+	d.depth++
+	defer func() { d.depth-- }()
+
 	// Read the '#bundle' OSC string
-	startTag, n, err := readPaddedString(reader)
+	startTag, err := d.readString("bundle tag", end)
 	if err != nil {
 		return nil, err
 	}
-	*start += n
 
 	if startTag != bundleTagString {
-		return nil, errors.New("Invalid bundle start tag: " + startTag)
+		return nil, d.error("bundle tag", fmt.Errorf("invalid bundle start tag %q", startTag))
 	}
 
 	// Read the timetag
-	var timeTag uint64
-	err = binary.Read(reader, binary.BigEndian, &timeTag)
+	b, err := d.read(8, "timetag", end)
 	if err != nil {
 		return nil, err
 	}
 
-	*start += 8
-
 	// Create a new bundle
-	bundle := NewBundle(timetagToTime(Timetag(timeTag)))
+	bundle := &Bundle{
+		Timetag:  Timetag(binary.BigEndian.Uint64(b)),
+		Messages: []*Message{},
+		Bundles:  []*Bundle{},
+	}
 
 	// Read until the end of the buffer
-	//
-	for (end - *start) > 4 {
-		// Read the size of the bundle element
-		var length int32
+	for d.offset < end {
+		if end-d.offset < 4 && !d.opts.Strict {
+			// ignore trailing bytes
+			break
+		}
 
-		err = binary.Read(reader, binary.BigEndian, &length)
+		// Read the size of the bundle element
+		b, err := d.read(4, "bundle element size", end)
 		if err != nil {
 			return nil, err
 		}
-		if length == 0 {
+		length := int32(binary.BigEndian.Uint32(b))
+
+		if length == 0 && !d.opts.Strict {
+			// zero padding after the last element
 			break
 		}
 
-		*start += 4
+		if length <= 0 || int(length) > end-d.offset {
+			d.offset -= 4
+			return nil, d.error("bundle element size", fmt.Errorf("invalid bundle element size %d", length))
+		}
+
+		if int(length) > d.opts.maxElementSize() {
+			d.offset -= 4
+			return nil, d.error("bundle element size", fmt.Errorf("%w: bundle element size %d > %d", ErrorLimitExceeded, length, d.opts.maxElementSize()))
+		}
+
+		elementEnd := d.offset + int(length)
 
-		p, err := readPacket(reader, start, end)
+		p, err := d.readPacket(elementEnd)
 		if err != nil {
 			return nil, err
 		}
 
+		if d.opts.Strict && d.offset != elementEnd {
+			return nil, d.error("bundle element", ErrorTrailingBytes)
+		}
+		d.offset = elementEnd
+
 		err = bundle.Append(p)
 		if err != nil {
 			return nil, err
@@ -90,19 +248,24 @@ func readBundle(reader *bufio.Reader, start *int, end int) (*Bundle, error) {
 	return bundle, nil
 }
 
-// readMessage from `reader`.
-func readMessage(reader *bufio.Reader, start *int) (*Message, error) {
+// readMessage reads an OSC Message.
+func (d *decoder) readMessage(end int) (*Message, error) {
 	// First, read the OSC address
-	addr, n, err := readPaddedString(reader)
+	addr, err := d.readString("address", end)
 	if err != nil {
 		return nil, err
 	}
-	*start += n
+
+	if d.opts.Strict {
+		if err := checkAddress(addr); err != nil {
+			return nil, d.error("address", err)
+		}
+	}
 
 	// Read all arguments
 	msg := NewMessage(addr)
 
-	err = readArguments(msg, reader, start)
+	err = d.readArguments(msg, end)
 	if err != nil {
 		return nil, err
 	}
@@ -110,98 +273,102 @@ func readMessage(reader *bufio.Reader, start *int) (*Message, error) {
 	return msg, nil
 }
 
-// readArguments from `reader` and add them to the OSC message `msg`.
-func readArguments(msg *Message, reader *bufio.Reader, start *int) error {
-	// Read the type tag string
-	var n int
-	typetags, n, err := readPaddedString(reader)
-	if err != nil {
-		return err
+// readArguments reads the type tag string and the arguments and adds them to
+// the OSC message `msg`.
+func (d *decoder) readArguments(msg *Message, end int) error {
+	// A message without type tag string and arguments
+	if d.offset == end {
+		if d.opts.Strict {
+			return d.error("type tag string", ErrorMissingTypeTags)
+		}
+		return nil
 	}
-	*start += n
 
-	if len(typetags) == 0 {
-		return nil
+	// If the typetag doesn't start with ',', it's not a type tag string
+	if d.data[d.offset] != ',' {
+		if d.opts.Lenient && !d.opts.Strict {
+			msg.Untyped = true
+			msg.RawArguments = append([]byte{}, d.data[d.offset:end]...)
+			d.offset = end
+			return nil
+		}
+		return d.error("type tag string", ErrorMissingTypeTags)
 	}
 
-	// If the typetag doesn't start with ',', it's not valid
-	if typetags[0] != ',' {
-		return fmt.Errorf("unsupported type tag string %s", typetags)
+	// Read the type tag string
+	typetags, err := d.readString("type tag string", end)
+	if err != nil {
+		return err
 	}
 
 	// Remove ',' from the type tag
 	typetags = typetags[1:]
 
-	for _, c := range typetags {
+	if len(typetags) > d.opts.maxArguments() {
+		return d.error("type tag string", fmt.Errorf("%w: %d arguments > %d", ErrorLimitExceeded, len(typetags), d.opts.maxArguments()))
+	}
+
+	for i, c := range typetags {
+		context := fmt.Sprintf("argument %d (%c)", i, c)
+
 		switch c {
 		case 'i': // int32
-			var i int32
-
-			err = binary.Read(reader, binary.BigEndian, &i)
+			b, err := d.read(4, context, end)
 			if err != nil {
 				return err
 			}
-
-			*start += 4
-			msg.Append(i)
+			msg.Append(int32(binary.BigEndian.Uint32(b)))
 
 		case 'h': // int64
-			var i int64
-			err = binary.Read(reader, binary.BigEndian, &i)
+			b, err := d.read(8, context, end)
 			if err != nil {
 				return err
 			}
-			*start += 8
-			msg.Append(i)
+			msg.Append(int64(binary.BigEndian.Uint64(b)))
 
 		case 'f': // float32
-			var f float32
-			err = binary.Read(reader, binary.BigEndian, &f)
+			b, err := d.read(4, context, end)
 			if err != nil {
 				return err
 			}
-			*start += 4
-			msg.Append(f)
+			msg.Append(math.Float32frombits(binary.BigEndian.Uint32(b)))
 
 		case 'd': // float64/double
-			var d float64
-			err = binary.Read(reader, binary.BigEndian, &d)
+			b, err := d.read(8, context, end)
 			if err != nil {
 				return err
 			}
-			*start += 8
-			msg.Append(d)
+			msg.Append(math.Float64frombits(binary.BigEndian.Uint64(b)))
 
 		case 's': // string
-			var s string
-			s, _, err = readPaddedString(reader)
+			s, err := d.readString(context, end)
 			if err != nil {
 				return err
 			}
-			lenStr := len(s)
-			*start += lenStr + padBytesNeeded(lenStr)
 			msg.Append(s)
 
 		case 'b': // blob
-			var buf []byte
-			var n int
-			buf, n, err = readBlob(reader)
+			blob, n, err := readBlob(d.data[d.offset:end])
 			if err != nil {
-				return err
+				return d.error(context, err)
 			}
-			*start += n
-			msg.Append(buf)
+			if len(blob) > d.opts.maxElementSize() {
+				return d.error(context, fmt.Errorf("%w: blob size %d > %d", ErrorLimitExceeded, len(blob), d.opts.maxElementSize()))
+			}
+			if d.opts.Strict {
+				if err := checkPadding(d.data[d.offset+4+len(blob) : d.offset+n]); err != nil {
+					return d.error(context, err)
+				}
+			}
+			d.offset += n
+			msg.Append(blob)
 
 		case 't': // OSC time tag
-			var tt uint64
-
-			err = binary.Read(reader, binary.BigEndian, &tt)
+			b, err := d.read(8, context, end)
 			if err != nil {
-				return nil
+				return err
 			}
-
-			*start += 8
-			msg.Append(Timetag(tt))
+			msg.Append(Timetag(binary.BigEndian.Uint64(b)))
 
 		case 'N': // nil
 			msg.Append(nil)
@@ -213,9 +380,32 @@ func readArguments(msg *Message, reader *bufio.Reader, start *int) error {
 			msg.Append(false)
 
 		default:
-			return fmt.Errorf("unsupported type tag: %c", c)
+			return d.error(context, fmt.Errorf("unsupported type tag: %c", c))
 		}
 	}
 
 	return nil
 }
+
+// checkPadding returns an error if not all padding bytes are zero.
+func checkPadding(pad []byte) error {
+	for _, b := range pad {
+		if b != 0 {
+			return ErrorInvalidPadding
+		}
+	}
+	return nil
+}
+
+// checkAddress returns an error if addr is not a valid OSC address pattern.
+func checkAddress(addr string) error {
+	if !strings.HasPrefix(addr, "/") {
+		return ErrorOscAddress
+	}
+	for _, c := range addr {
+		if c <= ' ' || c > '~' || c == '#' {
+			return ErrorOscAddress
+		}
+	}
+	return nil
+}
@@ -5,8 +5,11 @@ import (
 	"bytes"
 	"reflect"
 	"testing"
+	"time"
 
 	"bekuba.de/go-osc"
+
+	"github.com/stretchr/testify/assert"
 )
 
 func TestParsePacket(t *testing.T) {
@@ -84,3 +87,139 @@ func nulls(i int) string {
 	}
 	return s
 }
+
+func TestUnmarshalPacket(t *testing.T) {
+	msg := "/a/b" + nulls(4) + ",itb" + nulls(4) +
+		"\x00\x00\x00\x01" + "\x00\x00\x00\x00\x00\x00\x00\x02" + "\x00\x00\x00\x00"
+
+	for _, tt := range []struct {
+		desc   string
+		data   string
+		opts   osc.DecodeOptions
+		pkt    osc.Packet
+		offset int // offset of the DecodeError, -1 if no error is expected
+	}{
+		{"message", msg, osc.DecodeOptions{}, osc.NewMessage("/a/b", int32(1), osc.Timetag(2), []byte{}), -1},
+		{"strict message", msg, osc.DecodeOptions{Strict: true}, osc.NewMessage("/a/b", int32(1), osc.Timetag(2), []byte{}), -1},
+		{"truncated timetag", msg[:24], osc.DecodeOptions{}, nil, 20},
+		{"truncated blob", msg[:30], osc.DecodeOptions{}, nil, 28},
+		{"trailing bytes", msg + nulls(4), osc.DecodeOptions{}, osc.NewMessage("/a/b", int32(1), osc.Timetag(2), []byte{}), -1},
+		{"strict trailing bytes", msg + nulls(4), osc.DecodeOptions{Strict: true}, nil, 32},
+		{"padding", "/a\x00x,\x00\x00\x00", osc.DecodeOptions{}, osc.NewMessage("/a"), -1},
+		{"strict padding", "/a\x00x,\x00\x00\x00", osc.DecodeOptions{Strict: true}, nil, 0},
+		{"strict alignment", "/a\x00\x00,\x00\x00", osc.DecodeOptions{Strict: true}, nil, 0},
+		{"strict address", "/a b" + nulls(4) + "," + nulls(3), osc.DecodeOptions{Strict: true}, nil, 8},
+		{"no type tags", "/a/b" + nulls(4), osc.DecodeOptions{}, osc.NewMessage("/a/b"), -1},
+		{"strict no type tags", "/a/b" + nulls(4), osc.DecodeOptions{Strict: true}, nil, 8},
+		{"legacy args", "/a/b" + nulls(4) + "\x00\x00\x00\x01", osc.DecodeOptions{}, nil, 8},
+		{"lenient legacy args", "/a/b" + nulls(4) + "\x00\x00\x00\x01", osc.DecodeOptions{Lenient: true}, &osc.Message{Address: "/a/b", Untyped: true, RawArguments: []byte{0, 0, 0, 1}}, -1},
+		{"unsupported type tag", "/a/b" + nulls(4) + ",x" + nulls(2), osc.DecodeOptions{}, nil, 12},
+		{"invalid packet", "a/b" + nulls(1), osc.DecodeOptions{}, nil, 0},
+		{"empty", "", osc.DecodeOptions{}, nil, 0},
+	} {
+		t.Run(tt.desc, func(t *testing.T) {
+			pkt, err := tt.opts.UnmarshalPacket([]byte(tt.data))
+			if tt.offset < 0 {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.pkt, pkt)
+				return
+			}
+
+			var decodeErr *osc.DecodeError
+			if assert.ErrorAs(t, err, &decodeErr) {
+				assert.Equal(t, tt.offset, decodeErr.Offset)
+				assert.ErrorIs(t, err, osc.ErrorInvalidPacked)
+			}
+		})
+	}
+}
+
+func TestUnmarshalBundle(t *testing.T) {
+	bundle := osc.NewBundle(time.Now())
+	assert.NoError(t, bundle.Append(osc.NewMessage("/a", int32(1))))
+	nested := osc.NewBundle(time.Now())
+	assert.NoError(t, nested.Append(osc.NewMessage("/b", "test")))
+	assert.NoError(t, bundle.Append(nested))
+
+	data, err := bundle.MarshalBinary()
+	assert.NoError(t, err)
+
+	t.Run("should decode bundle", func(t *testing.T) {
+		pkt, err := osc.DecodeOptions{Strict: true}.UnmarshalPacket(data)
+		assert.NoError(t, err)
+		assert.Equal(t, bundle, pkt)
+	})
+
+	t.Run("should fail on invalid element size", func(t *testing.T) {
+		d := append([]byte{}, data...)
+		d[19] = 0xff
+
+		_, err := osc.UnmarshalPacket(d)
+		var decodeErr *osc.DecodeError
+		if assert.ErrorAs(t, err, &decodeErr) {
+			assert.Equal(t, 16, decodeErr.Offset)
+			assert.Equal(t, "bundle element size", decodeErr.Context)
+		}
+	})
+
+	t.Run("should accept zero padding only in default mode", func(t *testing.T) {
+		d := append(append([]byte{}, data...), 0, 0, 0, 0)
+
+		_, err := osc.UnmarshalPacket(d)
+		assert.NoError(t, err)
+
+		_, err = osc.DecodeOptions{Strict: true}.UnmarshalPacket(d)
+		assert.Error(t, err)
+	})
+}
+
+func TestDecodeLimits(t *testing.T) {
+	t.Run("should limit bundle depth", func(t *testing.T) {
+		bundle := osc.NewBundle(time.Now())
+		for i := 0; i < 20; i++ {
+			b := osc.NewBundle(time.Now())
+			assert.NoError(t, b.Append(bundle))
+			bundle = b
+		}
+		data, err := bundle.MarshalBinary()
+		assert.NoError(t, err)
+
+		_, err = osc.UnmarshalPacket(data)
+		assert.ErrorIs(t, err, osc.ErrorLimitExceeded)
+
+		_, err = osc.DecodeOptions{MaxBundleDepth: 21}.UnmarshalPacket(data)
+		assert.NoError(t, err)
+	})
+
+	t.Run("should limit number of arguments", func(t *testing.T) {
+		msg := osc.NewMessage("/args")
+		for i := 0; i < 10; i++ {
+			assert.NoError(t, msg.Append(true))
+		}
+		data, err := msg.MarshalBinary()
+		assert.NoError(t, err)
+
+		_, err = osc.DecodeOptions{MaxArguments: 9}.UnmarshalPacket(data)
+		assert.ErrorIs(t, err, osc.ErrorLimitExceeded)
+
+		_, err = osc.DecodeOptions{MaxArguments: 10}.UnmarshalPacket(data)
+		assert.NoError(t, err)
+	})
+
+	t.Run("should limit element size", func(t *testing.T) {
+		msg := osc.NewMessage("/blob", make([]byte, 100))
+		data, err := msg.MarshalBinary()
+		assert.NoError(t, err)
+
+		_, err = osc.DecodeOptions{MaxElementSize: 99}.UnmarshalPacket(data)
+		assert.ErrorIs(t, err, osc.ErrorLimitExceeded)
+
+		bundle := osc.NewBundle(time.Now())
+		assert.NoError(t, bundle.Append(msg))
+		data, err = bundle.MarshalBinary()
+		assert.NoError(t, err)
+
+		_, err = osc.DecodeOptions{MaxElementSize: 100}.UnmarshalPacket(data)
+		assert.ErrorIs(t, err, osc.ErrorLimitExceeded)
+	})
+}
@@ -0,0 +1,510 @@
+package osc
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/bits"
+	"net"
+	"time"
+)
+
+// Link types of captures
+const (
+	linkTypeNull     = 0
+	linkTypeEthernet = 1
+	linkTypeRaw      = 101
+	linkTypeLinuxSLL = 113
+	linkTypeIPv4     = 228
+	linkTypeIPv6     = 229
+)
+
+const (
+	pcapMagicMicros = 0xa1b2c3d4
+	pcapMagicNanos  = 0xa1b23c4d
+	// pcapMaxFrameSize is the maximum size of a captured frame.
+	pcapMaxFrameSize = 262144
+
+	pcapngSectionHeader        = 0x0a0d0d0a
+	pcapngByteOrderMagic       = 0x1a2b3c4d
+	pcapngInterfaceDescription = 1
+	pcapngSimplePacket         = 3
+	pcapngEnhancedPacket       = 6
+	// pcapngMaxBlockSize is the maximum size of a pcapng block.
+	pcapngMaxBlockSize = 16 << 20
+)
+
+// CapturedPacket is an OSC packet of a capture.
+type CapturedPacket struct {
+	// Time is the capture time. It is zero for pcapng simple packet blocks.
+	Time   time.Time
+	Src    *net.UDPAddr
+	Dst    *net.UDPAddr
+	Packet Packet
+}
+
+// pcapngInterface is an interface of a pcapng section.
+type pcapngInterface struct {
+	linkType int
+	// ticks per second of the timestamps
+	resolution uint64
+}
+
+// PcapReader reads the OSC packets of a pcap or pcapng capture (e.g. of
+// tcpdump or Wireshark) of Ethernet, Linux cooked, loopback or raw IP frames.
+// UDP datagrams, that aren't valid OSC packets, and fragmented IP packets are
+// skipped.
+type PcapReader struct {
+	// Port filters the UDP datagrams by source or destination port. Zero
+	// means all ports.
+	Port int
+	// DecodeOptions controls how the OSC packets are decoded.
+	DecodeOptions DecodeOptions
+
+	r     io.Reader
+	order binary.ByteOrder
+	ng    bool
+	// pcap
+	linkType   int
+	resolution uint64
+	// pcapng
+	interfaces []pcapngInterface
+}
+
+// NewPcapReader reads the header of a pcap or pcapng capture.
+func NewPcapReader(r io.Reader) (*PcapReader, error) {
+	pr := &PcapReader{r: r}
+
+	magic := make([]byte, 4)
+	if _, err := io.ReadFull(r, magic); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrorInvalidCapture, err)
+	}
+
+	if binary.BigEndian.Uint32(magic) == pcapngSectionHeader {
+		pr.ng = true
+		if err := pr.readSectionHeader(); err != nil {
+			return nil, err
+		}
+		return pr, nil
+	}
+
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		switch order.Uint32(magic) {
+		case pcapMagicMicros:
+			pr.order, pr.resolution = order, 1e6
+		case pcapMagicNanos:
+			pr.order, pr.resolution = order, 1e9
+		}
+	}
+	if pr.order == nil {
+		return nil, fmt.Errorf("%w: unknown file format", ErrorInvalidCapture)
+	}
+
+	header := make([]byte, 20)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrorInvalidCapture, err)
+	}
+	pr.linkType = int(pr.order.Uint32(header[16:]) & 0xffff)
+	return pr, nil
+}
+
+// Next returns the next OSC packet or io.EOF at the end of the capture.
+func (r *PcapReader) Next() (*CapturedPacket, error) {
+	for {
+		frame, t, linkType, err := r.readFrame()
+		if err != nil {
+			return nil, err
+		}
+
+		src, dst, payload, ok := parseFrame(frame, linkType)
+		if !ok {
+			continue
+		}
+		if r.Port != 0 && src.Port != r.Port && dst.Port != r.Port {
+			continue
+		}
+		packet, err := r.DecodeOptions.UnmarshalPacket(payload)
+		if err != nil {
+			continue
+		}
+		return &CapturedPacket{Time: t, Src: src, Dst: dst, Packet: packet}, nil
+	}
+}
+
+// readFrame reads the next captured frame.
+func (r *PcapReader) readFrame() ([]byte, time.Time, int, error) {
+	if r.ng {
+		return r.readBlockFrame()
+	}
+
+	header := make([]byte, 16)
+	if _, err := io.ReadFull(r.r, header); err != nil {
+		return nil, time.Time{}, 0, err
+	}
+	size := r.order.Uint32(header[8:])
+	if size > pcapMaxFrameSize {
+		return nil, time.Time{}, 0, fmt.Errorf("%w: frame of %d bytes", ErrorInvalidCapture, size)
+	}
+	frame := make([]byte, size)
+	if _, err := io.ReadFull(r.r, frame); err != nil {
+		return nil, time.Time{}, 0, io.ErrUnexpectedEOF
+	}
+
+	sec := uint64(r.order.Uint32(header[0:]))
+	frac := uint64(r.order.Uint32(header[4:]))
+	return frame, captureTime(sec*r.resolution+frac, r.resolution), r.linkType, nil
+}
+
+// readSectionHeader reads a pcapng section header block after the block type.
+func (r *PcapReader) readSectionHeader() error {
+	buf := make([]byte, 8)
+	if _, err := io.ReadFull(r.r, buf); err != nil {
+		return fmt.Errorf("%w: %v", ErrorInvalidCapture, err)
+	}
+
+	switch uint32(pcapngByteOrderMagic) {
+	case binary.LittleEndian.Uint32(buf[4:]):
+		r.order = binary.LittleEndian
+	case binary.BigEndian.Uint32(buf[4:]):
+		r.order = binary.BigEndian
+	default:
+		return fmt.Errorf("%w: invalid byte-order magic", ErrorInvalidCapture)
+	}
+
+	size := r.order.Uint32(buf)
+	if size < 16 || size%4 != 0 || size > pcapngMaxBlockSize {
+		return fmt.Errorf("%w: section header block of %d bytes", ErrorInvalidCapture, size)
+	}
+	// version, section length, options and the trailing block length
+	if _, err := io.CopyN(io.Discard, r.r, int64(size-12)); err != nil {
+		return io.ErrUnexpectedEOF
+	}
+
+	r.interfaces = nil
+	return nil
+}
+
+// readBlockFrame reads the pcapng blocks until the next captured frame.
+func (r *PcapReader) readBlockFrame() ([]byte, time.Time, int, error) {
+	for {
+		header := make([]byte, 4)
+		if _, err := io.ReadFull(r.r, header); err != nil {
+			return nil, time.Time{}, 0, err
+		}
+		blockType := r.order.Uint32(header)
+		if blockType == pcapngSectionHeader {
+			if err := r.readSectionHeader(); err != nil {
+				return nil, time.Time{}, 0, err
+			}
+			continue
+		}
+
+		if _, err := io.ReadFull(r.r, header); err != nil {
+			return nil, time.Time{}, 0, io.ErrUnexpectedEOF
+		}
+		size := r.order.Uint32(header)
+		if size < 12 || size%4 != 0 || size > pcapngMaxBlockSize {
+			return nil, time.Time{}, 0, fmt.Errorf("%w: block of %d bytes", ErrorInvalidCapture, size)
+		}
+		block := make([]byte, size-8)
+		if _, err := io.ReadFull(r.r, block); err != nil {
+			return nil, time.Time{}, 0, io.ErrUnexpectedEOF
+		}
+		// without the trailing block length
+		body := block[:len(block)-4]
+
+		switch blockType {
+		case pcapngInterfaceDescription:
+			if len(body) < 8 {
+				return nil, time.Time{}, 0, fmt.Errorf("%w: interface description block", ErrorInvalidCapture)
+			}
+			r.interfaces = append(r.interfaces, pcapngInterface{
+				linkType:   int(r.order.Uint16(body)),
+				resolution: r.tsResolution(body[8:]),
+			})
+
+		case pcapngEnhancedPacket:
+			if len(body) < 20 {
+				return nil, time.Time{}, 0, fmt.Errorf("%w: enhanced packet block", ErrorInvalidCapture)
+			}
+			id := int(r.order.Uint32(body))
+			size := int(r.order.Uint32(body[12:]))
+			if id >= len(r.interfaces) || size > len(body)-20 {
+				return nil, time.Time{}, 0, fmt.Errorf("%w: enhanced packet block", ErrorInvalidCapture)
+			}
+			iface := r.interfaces[id]
+			ts := uint64(r.order.Uint32(body[4:]))<<32 | uint64(r.order.Uint32(body[8:]))
+			return body[20 : 20+size], captureTime(ts, iface.resolution), iface.linkType, nil
+
+		case pcapngSimplePacket:
+			if len(body) < 4 || len(r.interfaces) == 0 {
+				return nil, time.Time{}, 0, fmt.Errorf("%w: simple packet block", ErrorInvalidCapture)
+			}
+			frame := body[4:]
+			if size := int(r.order.Uint32(body)); size < len(frame) {
+				frame = frame[:size]
+			}
+			return frame, time.Time{}, r.interfaces[0].linkType, nil
+		}
+	}
+}
+
+// tsResolution returns the ticks per second of the options of an interface
+// description block.
+func (r *PcapReader) tsResolution(options []byte) uint64 {
+	for len(options) >= 4 {
+		code := r.order.Uint16(options)
+		size := int(r.order.Uint16(options[2:]))
+		options = options[4:]
+		if code == 0 || size > len(options) {
+			break
+		}
+		// if_tsresol
+		if code == 9 && size == 1 {
+			v := options[0]
+			if v&0x80 != 0 {
+				return 1 << min(v&0x7f, 63)
+			}
+			resolution := uint64(1)
+			for i := byte(0); i < min(v, 19); i++ {
+				resolution *= 10
+			}
+			return resolution
+		}
+		options = options[min((size+3)&^3, len(options)):]
+	}
+	return 1e6
+}
+
+// captureTime returns the time of a timestamp with resolution ticks per
+// second.
+func captureTime(ts, resolution uint64) time.Time {
+	sec, frac := ts/resolution, ts%resolution
+	hi, lo := bits.Mul64(frac, 1e9)
+	nsec, _ := bits.Div64(hi, lo, resolution)
+	return time.Unix(int64(sec), int64(nsec))
+}
+
+// parseFrame returns the endpoints and the payload of a UDP datagram in frame.
+func parseFrame(frame []byte, linkType int) (src, dst *net.UDPAddr, payload []byte, ok bool) {
+	switch linkType {
+	case linkTypeNull:
+		// the address family in the byte order of the capturing host
+		if len(frame) < 4 {
+			return nil, nil, nil, false
+		}
+		return parseIP(frame[4:])
+
+	case linkTypeEthernet:
+		if len(frame) < 14 {
+			return nil, nil, nil, false
+		}
+		etherType, frame := binary.BigEndian.Uint16(frame[12:]), frame[14:]
+		// VLAN tags
+		for (etherType == 0x8100 || etherType == 0x88a8) && len(frame) >= 4 {
+			etherType, frame = binary.BigEndian.Uint16(frame[2:]), frame[4:]
+		}
+		if etherType != 0x0800 && etherType != 0x86dd {
+			return nil, nil, nil, false
+		}
+		return parseIP(frame)
+
+	case linkTypeLinuxSLL:
+		if len(frame) < 16 {
+			return nil, nil, nil, false
+		}
+		return parseIP(frame[16:])
+
+	case linkTypeRaw, linkTypeIPv4, linkTypeIPv6:
+		return parseIP(frame)
+	}
+	return nil, nil, nil, false
+}
+
+// parseIP returns the endpoints and the payload of a UDP datagram in an IPv4
+// or IPv6 packet.
+func parseIP(packet []byte) (src, dst *net.UDPAddr, payload []byte, ok bool) {
+	if len(packet) == 0 {
+		return nil, nil, nil, false
+	}
+
+	var srcIP, dstIP net.IP
+	switch packet[0] >> 4 {
+	case 4:
+		if len(packet) < 20 {
+			return nil, nil, nil, false
+		}
+		headerSize := int(packet[0]&0x0f) * 4
+		total := int(binary.BigEndian.Uint16(packet[2:]))
+		// fragments
+		if binary.BigEndian.Uint16(packet[6:])&0x3fff != 0 || packet[9] != 17 {
+			return nil, nil, nil, false
+		}
+		if headerSize < 20 || total < headerSize || total > len(packet) {
+			return nil, nil, nil, false
+		}
+		srcIP, dstIP = net.IP(packet[12:16]), net.IP(packet[16:20])
+		packet = packet[headerSize:total]
+
+	case 6:
+		if len(packet) < 40 {
+			return nil, nil, nil, false
+		}
+		total := 40 + int(binary.BigEndian.Uint16(packet[4:]))
+		if total > len(packet) {
+			return nil, nil, nil, false
+		}
+		next := packet[6]
+		srcIP, dstIP = net.IP(packet[8:24]), net.IP(packet[24:40])
+		packet = packet[40:total]
+
+		// hop-by-hop, routing and destination options extension headers
+		for next == 0 || next == 43 || next == 60 {
+			if len(packet) < 8 || (int(packet[1])+1)*8 > len(packet) {
+				return nil, nil, nil, false
+			}
+			next, packet = packet[0], packet[(int(packet[1])+1)*8:]
+		}
+		if next != 17 {
+			return nil, nil, nil, false
+		}
+
+	default:
+		return nil, nil, nil, false
+	}
+
+	if len(packet) < 8 {
+		return nil, nil, nil, false
+	}
+	size := int(binary.BigEndian.Uint16(packet[4:]))
+	if size < 8 || size > len(packet) {
+		return nil, nil, nil, false
+	}
+	src = &net.UDPAddr{IP: append(net.IP(nil), srcIP...), Port: int(binary.BigEndian.Uint16(packet))}
+	dst = &net.UDPAddr{IP: append(net.IP(nil), dstIP...), Port: int(binary.BigEndian.Uint16(packet[2:]))}
+	return src, dst, packet[8:size], true
+}
+
+// PcapWriter writes OSC packets as UDP datagrams of raw IP frames to a pcap
+// capture with nanosecond timestamps, e.g. for Wireshark.
+type PcapWriter struct {
+	w io.Writer
+}
+
+// NewPcapWriter writes the header of a pcap capture to w.
+func NewPcapWriter(w io.Writer) (*PcapWriter, error) {
+	header := binary.LittleEndian.AppendUint32(nil, pcapMagicNanos)
+	header = binary.LittleEndian.AppendUint16(header, 2)
+	header = binary.LittleEndian.AppendUint16(header, 4)
+	// time zone and accuracy
+	header = binary.LittleEndian.AppendUint64(header, 0)
+	header = binary.LittleEndian.AppendUint32(header, 65535)
+	header = binary.LittleEndian.AppendUint32(header, linkTypeRaw)
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+	return &PcapWriter{w: w}, nil
+}
+
+// WritePacket writes an OSC packet from src to dst captured at t. If src and
+// dst aren't both IPv4 addresses, an IPv6 frame is written. A nil address is
+// written as unspecified address with port 0.
+func (w *PcapWriter) WritePacket(t time.Time, src, dst *net.UDPAddr, packet Packet) error {
+	data, err := packet.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	if src == nil {
+		src = &net.UDPAddr{}
+	}
+	if dst == nil {
+		dst = &net.UDPAddr{}
+	}
+	srcIP, dstIP := src.IP, dst.IP
+	if srcIP == nil {
+		srcIP = net.IPv4zero
+	}
+	if dstIP == nil {
+		dstIP = net.IPv4zero
+	}
+
+	// IPv4 header, UDP header
+	if 20+8+len(data) > 0xffff {
+		return fmt.Errorf("%w: %d bytes (max. %d bytes)", ErrorPacketTooLarge, len(data), 0xffff-28)
+	}
+	udpSize := 8 + len(data)
+	udp := binary.BigEndian.AppendUint16(nil, uint16(src.Port))
+	udp = binary.BigEndian.AppendUint16(udp, uint16(dst.Port))
+	udp = binary.BigEndian.AppendUint16(udp, uint16(udpSize))
+	udp = binary.BigEndian.AppendUint16(udp, 0)
+	udp = append(udp, data...)
+
+	var frame []byte
+	if src4, dst4 := srcIP.To4(), dstIP.To4(); src4 != nil && dst4 != nil {
+		pseudo := append(append(append([]byte{}, src4...), dst4...), 0, 17)
+		pseudo = binary.BigEndian.AppendUint16(pseudo, uint16(udpSize))
+		binary.BigEndian.PutUint16(udp[6:], udpChecksum(pseudo, udp))
+
+		frame = []byte{0x45, 0}
+		frame = binary.BigEndian.AppendUint16(frame, uint16(20+udpSize))
+		// identification, don't fragment, TTL, protocol, checksum
+		frame = append(frame, 0, 0, 0x40, 0, 64, 17, 0, 0)
+		frame = append(append(frame, src4...), dst4...)
+		binary.BigEndian.PutUint16(frame[10:], ^checksum(0, frame))
+	} else {
+		src16, dst16 := srcIP.To16(), dstIP.To16()
+		pseudo := append(append([]byte{}, src16...), dst16...)
+		pseudo = binary.BigEndian.AppendUint32(pseudo, uint32(udpSize))
+		pseudo = append(pseudo, 0, 0, 0, 17)
+		binary.BigEndian.PutUint16(udp[6:], udpChecksum(pseudo, udp))
+
+		frame = []byte{0x60, 0, 0, 0}
+		frame = binary.BigEndian.AppendUint16(frame, uint16(udpSize))
+		// next header, hop limit
+		frame = append(frame, 17, 64)
+		frame = append(append(frame, src16...), dst16...)
+	}
+	frame = append(frame, udp...)
+
+	record := binary.LittleEndian.AppendUint32(nil, uint32(t.Unix()))
+	record = binary.LittleEndian.AppendUint32(record, uint32(t.Nanosecond()))
+	record = binary.LittleEndian.AppendUint32(record, uint32(len(frame)))
+	record = binary.LittleEndian.AppendUint32(record, uint32(len(frame)))
+	_, err = w.w.Write(append(record, frame...))
+	return err
+}
+
+// WriteRecording writes all records of a recording (see Player) as sent to
+// dst. Sources without UDP address are written as unspecified address.
+func (w *PcapWriter) WriteRecording(p *Player, dst *net.UDPAddr) error {
+	for _, rec := range p.Records {
+		src, _ := rec.Addr.(*net.UDPAddr)
+		if err := w.WritePacket(p.Start.Add(rec.Offset), src, dst, rec.Packet); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checksum adds data to the ones' complement sum.
+func checksum(sum uint32, data []byte) uint16 {
+	for i := 0; i+1 < len(data); i += 2 {
+		sum += uint32(binary.BigEndian.Uint16(data[i:]))
+	}
+	if len(data)%2 == 1 {
+		sum += uint32(data[len(data)-1]) << 8
+	}
+	for sum > 0xffff {
+		sum = sum>>16 + sum&0xffff
+	}
+	return uint16(sum)
+}
+
+// udpChecksum returns the UDP checksum of the pseudo header and the datagram.
+func udpChecksum(pseudo, udp []byte) uint16 {
+	sum := ^checksum(uint32(checksum(0, pseudo)), udp)
+	if sum == 0 {
+		return 0xffff
+	}
+	return sum
+}
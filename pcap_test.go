@@ -0,0 +1,178 @@
+package osc_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"bekuba.de/go-osc"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// onesComplementSum returns the ones' complement sum of data, that is 0xffff
+// for data with a valid checksum.
+func onesComplementSum(data []byte) uint16 {
+	var sum uint32
+	for i := 0; i+1 < len(data); i += 2 {
+		sum += uint32(binary.BigEndian.Uint16(data[i:]))
+	}
+	if len(data)%2 == 1 {
+		sum += uint32(data[len(data)-1]) << 8
+	}
+	for sum > 0xffff {
+		sum = sum>>16 + sum&0xffff
+	}
+	return uint16(sum)
+}
+
+func TestPcapWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := osc.NewPcapWriter(&buf)
+	assert.NoError(t, err)
+
+	t0 := time.Date(2024, 5, 1, 20, 0, 0, 123456789, time.UTC)
+	console := &net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 9000}
+	mixer := &net.UDPAddr{IP: net.IPv4(10, 0, 0, 2), Port: 10024}
+	console6 := &net.UDPAddr{IP: net.ParseIP("fd00::1"), Port: 9000}
+	mixer6 := &net.UDPAddr{IP: net.ParseIP("fd00::2"), Port: 10024}
+
+	assert.NoError(t, w.WritePacket(t0, console, mixer, osc.NewMessage("/ch/01/mix/fader", float32(0.75))))
+	assert.NoError(t, w.WritePacket(t0.Add(time.Millisecond), console6, mixer6, osc.NewMessage("/xinfo")))
+	assert.NoError(t, w.WritePacket(t0.Add(2*time.Millisecond), console, &net.UDPAddr{IP: net.IPv4(10, 0, 0, 3), Port: 8000}, osc.NewMessage("/other")))
+	assert.ErrorIs(t, w.WritePacket(t0, console, mixer, osc.NewMessage("/blob", make([]byte, 0xffff))), osc.ErrorPacketTooLarge)
+
+	// IPv4 header and UDP checksums of the first frame
+	frame := buf.Bytes()[24+16:]
+	frame = frame[:binary.LittleEndian.Uint32(buf.Bytes()[24+8:])]
+	assert.Equal(t, uint16(0xffff), onesComplementSum(frame[:20]))
+	pseudo := append(append([]byte{}, frame[12:20]...), 0, 17, frame[24], frame[25])
+	assert.Equal(t, uint16(0xffff), onesComplementSum(append(pseudo, frame[20:]...)))
+
+	r, err := osc.NewPcapReader(bytes.NewReader(buf.Bytes()))
+	assert.NoError(t, err)
+	r.Port = 10024
+
+	p, err := r.Next()
+	assert.NoError(t, err)
+	assert.True(t, t0.Equal(p.Time))
+	assert.Equal(t, console.String(), p.Src.String())
+	assert.Equal(t, mixer.String(), p.Dst.String())
+	assert.Equal(t, osc.NewMessage("/ch/01/mix/fader", float32(0.75)), p.Packet)
+
+	p, err = r.Next()
+	assert.NoError(t, err)
+	assert.True(t, t0.Add(time.Millisecond).Equal(p.Time))
+	assert.Equal(t, console6.String(), p.Src.String())
+	assert.Equal(t, mixer6.String(), p.Dst.String())
+	assert.Equal(t, osc.NewMessage("/xinfo"), p.Packet)
+
+	// filtered by port
+	_, err = r.Next()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestPcapWriterRecording(t *testing.T) {
+	player := newRecording(3, time.Millisecond)
+	player.Start = time.Unix(1700000000, 0)
+	player.Records[2].Addr = &osc.RecordedAddr{Net: "tls", Addr: "10.0.0.5:443"}
+
+	var buf bytes.Buffer
+	w, err := osc.NewPcapWriter(&buf)
+	assert.NoError(t, err)
+	assert.NoError(t, w.WriteRecording(player, &net.UDPAddr{IP: net.IPv4(10, 0, 0, 2), Port: 8000}))
+
+	r, err := osc.NewPcapReader(&buf)
+	assert.NoError(t, err)
+	for i, rec := range player.Records {
+		p, err := r.Next()
+		assert.NoError(t, err)
+		assert.True(t, player.Start.Add(rec.Offset).Equal(p.Time))
+		assert.Equal(t, rec.Packet, p.Packet)
+		if i < 2 {
+			assert.Equal(t, "10.0.0.1:9000", p.Src.String())
+		} else {
+			assert.Equal(t, "0.0.0.0:0", p.Src.String())
+		}
+	}
+}
+
+// udpIPv4 returns an IPv4 packet with a UDP datagram (without checksums).
+func udpIPv4(src, dst *net.UDPAddr, payload []byte) []byte {
+	packet := []byte{0x45, 0}
+	packet = binary.BigEndian.AppendUint16(packet, uint16(28+len(payload)))
+	packet = append(packet, 0, 0, 0, 0, 64, 17, 0, 0)
+	packet = append(append(packet, src.IP.To4()...), dst.IP.To4()...)
+	packet = binary.BigEndian.AppendUint16(packet, uint16(src.Port))
+	packet = binary.BigEndian.AppendUint16(packet, uint16(dst.Port))
+	packet = binary.BigEndian.AppendUint16(packet, uint16(8+len(payload)))
+	packet = binary.BigEndian.AppendUint16(packet, 0)
+	return append(packet, payload...)
+}
+
+// pcapngBlock returns a little-endian pcapng block.
+func pcapngBlock(blockType uint32, body []byte) []byte {
+	for len(body)%4 != 0 {
+		body = append(body, 0)
+	}
+	block := binary.LittleEndian.AppendUint32(nil, blockType)
+	block = binary.LittleEndian.AppendUint32(block, uint32(12+len(body)))
+	block = append(block, body...)
+	return binary.LittleEndian.AppendUint32(block, uint32(12+len(body)))
+}
+
+func TestPcapng(t *testing.T) {
+	src := &net.UDPAddr{IP: net.IPv4(192, 168, 1, 10), Port: 53000}
+	dst := &net.UDPAddr{IP: net.IPv4(192, 168, 1, 20), Port: 8000}
+	msg, err := osc.NewMessage("/cue/go", int32(7)).MarshalBinary()
+	assert.NoError(t, err)
+
+	var capture []byte
+	// section header: byte-order magic, version 1.0, unknown section length
+	shb := binary.LittleEndian.AppendUint32(nil, 0x1a2b3c4d)
+	shb = append(shb, 1, 0, 0, 0, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff)
+	capture = append(capture, pcapngBlock(0x0a0d0d0a, shb)...)
+
+	// Ethernet interface with nanosecond timestamps (if_tsresol 9)
+	idb := []byte{1, 0, 0, 0, 0, 0, 4, 0, 9, 0, 1, 0, 9, 0, 0, 0, 0, 0, 0, 0}
+	capture = append(capture, pcapngBlock(1, idb)...)
+
+	ts := time.Date(2024, 5, 1, 20, 0, 0, 5, time.UTC)
+	epb := func(frame []byte) []byte {
+		nanos := uint64(ts.UnixNano())
+		body := binary.LittleEndian.AppendUint32(nil, 0)
+		body = binary.LittleEndian.AppendUint32(body, uint32(nanos>>32))
+		body = binary.LittleEndian.AppendUint32(body, uint32(nanos))
+		body = binary.LittleEndian.AppendUint32(body, uint32(len(frame)))
+		body = binary.LittleEndian.AppendUint32(body, uint32(len(frame)))
+		return pcapngBlock(6, append(body, frame...))
+	}
+	ethernet := func(etherType []byte, payload []byte) []byte {
+		frame := append(make([]byte, 12), etherType...)
+		return append(frame, payload...)
+	}
+
+	// ARP, no OSC payload, VLAN-tagged OSC with Ethernet padding
+	capture = append(capture, epb(ethernet([]byte{0x08, 0x06}, make([]byte, 28)))...)
+	capture = append(capture, epb(ethernet([]byte{0x08, 0x00}, udpIPv4(src, dst, []byte("hello"))))...)
+	frame := ethernet([]byte{0x81, 0x00, 0x00, 0x05, 0x08, 0x00}, udpIPv4(src, dst, msg))
+	capture = append(capture, epb(append(frame, make([]byte, 6)...))...)
+
+	r, err := osc.NewPcapReader(bytes.NewReader(capture))
+	assert.NoError(t, err)
+	p, err := r.Next()
+	assert.NoError(t, err)
+	assert.True(t, ts.Equal(p.Time))
+	assert.Equal(t, src.String(), p.Src.String())
+	assert.Equal(t, dst.String(), p.Dst.String())
+	assert.Equal(t, osc.NewMessage("/cue/go", int32(7)), p.Packet)
+
+	_, err = r.Next()
+	assert.Equal(t, io.EOF, err)
+
+	_, err = osc.NewPcapReader(bytes.NewReader([]byte("#bundle\x00")))
+	assert.ErrorIs(t, err, osc.ErrorInvalidCapture)
+}
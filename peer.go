@@ -0,0 +1,160 @@
+package osc
+
+import (
+	"net"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Peer holds the statistics of a remote address, that sent packets to a
+// node.
+type Peer struct {
+	Addr      net.Addr
+	FirstSeen time.Time
+	LastSeen  time.Time
+	// Packets is the number of received packets, including invalid packets.
+	Packets uint64
+	// Bytes is the number of received bytes.
+	Bytes uint64
+	// Errors is the number of packets, that couldn't be decoded.
+	Errors uint64
+}
+
+// PeerRegistry records all remote addresses, that send packets to a node
+// (see Node.Peers).
+type PeerRegistry struct {
+	// IdleTimeout is the time without packets, after which a peer is removed.
+	// Zero means peers are never removed.
+	IdleTimeout time.Duration
+
+	// PeerJoined is called for the first packet of a new peer.
+	PeerJoined func(peer Peer)
+
+	// PeerLeft is called, if a peer is removed after IdleTimeout.
+	PeerLeft func(peer Peer)
+
+	mutex sync.Mutex
+	peers map[string]*Peer
+	timer *time.Timer
+}
+
+// NewPeerRegistry returns a PeerRegistry, that removes peers after
+// idleTimeout.
+func NewPeerRegistry(idleTimeout time.Duration) *PeerRegistry {
+	return &PeerRegistry{
+		IdleTimeout: idleTimeout,
+		peers:       make(map[string]*Peer),
+	}
+}
+
+// Peers returns all active peers, ordered by the time they joined.
+func (r *PeerRegistry) Peers() []Peer {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	peers := make([]Peer, 0, len(r.peers))
+	for _, p := range r.peers {
+		peers = append(peers, *p)
+	}
+	sort.Slice(peers, func(i, j int) bool {
+		return peers[i].FirstSeen.Before(peers[j].FirstSeen)
+	})
+
+	return peers
+}
+
+// Peer returns the peer with the address addr.
+func (r *PeerRegistry) Peer(addr net.Addr) (Peer, bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	p, ok := r.peers[peerKey(addr)]
+	if !ok {
+		return Peer{}, false
+	}
+	return *p, true
+}
+
+// Addrs returns the addresses of all active peers.
+func (r *PeerRegistry) Addrs() []net.Addr {
+	peers := r.Peers()
+	addrs := make([]net.Addr, len(peers))
+	for i, p := range peers {
+		addrs[i] = p.Addr
+	}
+	return addrs
+}
+
+// record records a received packet of n bytes. failed is true, if the packet
+// couldn't be decoded.
+func (r *PeerRegistry) record(addr net.Addr, n int, failed bool) {
+	if addr == nil {
+		return
+	}
+
+	now := time.Now()
+	key := peerKey(addr)
+
+	r.mutex.Lock()
+	if r.peers == nil {
+		r.peers = make(map[string]*Peer)
+	}
+	p, ok := r.peers[key]
+	if !ok {
+		p = &Peer{Addr: addr, FirstSeen: now}
+		r.peers[key] = p
+	}
+	p.LastSeen = now
+	p.Packets++
+	p.Bytes += uint64(n)
+	if failed {
+		p.Errors++
+	}
+	joined := *p
+
+	if r.IdleTimeout > 0 && r.timer == nil {
+		r.timer = time.AfterFunc(r.IdleTimeout, r.expire)
+	}
+	r.mutex.Unlock()
+
+	if !ok && r.PeerJoined != nil {
+		r.PeerJoined(joined)
+	}
+}
+
+// expire removes all idle peers and restarts the timer for the next idle
+// peer.
+func (r *PeerRegistry) expire() {
+	now := time.Now()
+	var left []Peer
+
+	r.mutex.Lock()
+	next := r.IdleTimeout
+	for key, p := range r.peers {
+		idle := now.Sub(p.LastSeen)
+		if idle >= r.IdleTimeout {
+			left = append(left, *p)
+			delete(r.peers, key)
+		} else if r.IdleTimeout-idle < next {
+			next = r.IdleTimeout - idle
+		}
+	}
+	if len(r.peers) > 0 {
+		r.timer.Reset(next)
+	} else {
+		r.timer = nil
+	}
+	r.mutex.Unlock()
+
+	if r.PeerLeft != nil {
+		for _, p := range left {
+			r.PeerLeft(p)
+		}
+	}
+}
+
+// peerKey returns the key of addr in the registry.
+func peerKey(addr net.Addr) string {
+	return addr.Network() + ":" + addr.String()
+}
@@ -0,0 +1,92 @@
+package osc_test
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"bekuba.de/go-osc"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPeerRegistry(t *testing.T) {
+	server, err := osc.NewNode("127.0.0.1:0")
+	assert.NoError(t, err)
+	defer server.Close()
+	addr := server.Conn().LocalAddr().String()
+
+	joined := make(chan osc.Peer, 10)
+	left := make(chan osc.Peer, 10)
+	server.Peers = osc.NewPeerRegistry(200 * time.Millisecond)
+	server.Peers.PeerJoined = func(peer osc.Peer) { joined <- peer }
+	server.Peers.PeerLeft = func(peer osc.Peer) { left <- peer }
+
+	d, received := newMessageReceiver(t)
+	go server.ListenAndServe(d)
+
+	client1, err := osc.NewNode("127.0.0.1:0")
+	assert.NoError(t, err)
+	defer client1.Close()
+	client2, err := osc.NewNode("127.0.0.1:0")
+	assert.NoError(t, err)
+	defer client2.Close()
+
+	// client1 sends two messages and an invalid packet
+	assert.NoError(t, client1.SendMsgTo(addr, ping, 1))
+	<-received
+	assert.NoError(t, client1.SendMsgTo(addr, ping, 2))
+	<-received
+	_, err = client1.Conn().WriteTo([]byte("invalid"), server.Conn().LocalAddr())
+	assert.NoError(t, err)
+
+	// client2 sends one message
+	time.Sleep(50 * time.Millisecond)
+	assert.NoError(t, client2.SendMsgTo(addr, ping))
+	<-received
+
+	p := <-joined
+	assert.Equal(t, client1.Conn().LocalAddr().String(), p.Addr.String())
+	p = <-joined
+	assert.Equal(t, client2.Conn().LocalAddr().String(), p.Addr.String())
+
+	peers := server.Peers.Peers()
+	if assert.Equal(t, 2, len(peers)) {
+		assert.Equal(t, client1.Conn().LocalAddr().String(), peers[0].Addr.String())
+		assert.Equal(t, uint64(3), peers[0].Packets)
+		assert.Equal(t, uint64(2*16+7), peers[0].Bytes)
+		assert.Equal(t, uint64(1), peers[0].Errors)
+
+		assert.Equal(t, client2.Conn().LocalAddr().String(), peers[1].Addr.String())
+		assert.Equal(t, uint64(1), peers[1].Packets)
+		assert.Equal(t, uint64(0), peers[1].Errors)
+		assert.False(t, peers[1].LastSeen.Before(peers[1].FirstSeen))
+	}
+
+	p2, ok := server.Peers.Peer(client2.Conn().LocalAddr())
+	assert.True(t, ok)
+	assert.Equal(t, peers[1], p2)
+	assert.Equal(t, []net.Addr{peers[0].Addr, peers[1].Addr}, server.Peers.Addrs())
+
+	// client1 leaves first, client2 keeps sending
+	for i := 0; i < 3; i++ {
+		time.Sleep(100 * time.Millisecond)
+		assert.NoError(t, client2.SendMsgTo(addr, ping))
+		<-received
+	}
+	select {
+	case p := <-left:
+		assert.Equal(t, client1.Conn().LocalAddr().String(), p.Addr.String())
+	case <-time.After(time.Second):
+		t.Fatal("client1 didn't leave")
+	}
+	assert.Equal(t, 1, len(server.Peers.Peers()))
+
+	select {
+	case p := <-left:
+		assert.Equal(t, client2.Conn().LocalAddr().String(), p.Addr.String())
+	case <-time.After(time.Second):
+		t.Fatal("client2 didn't leave")
+	}
+	assert.Equal(t, 0, len(server.Peers.Peers()))
+}
@@ -0,0 +1,359 @@
+package osc
+
+import (
+	"math"
+	"net"
+	"regexp"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// rateLimitPurgeInterval is the interval in which idle sources are removed.
+const rateLimitPurgeInterval = 10 * time.Second
+
+// RateLimit is a token bucket, that allows Rate messages per second on average
+// and bursts of up to Burst messages. A zero Rate means unlimited.
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+// RatePolicy defines what happens to messages exceeding a RateLimit.
+type RatePolicy int
+
+const (
+	// RateDrop drops messages exceeding the rate limit.
+	RateDrop RatePolicy = iota
+	// RateQueue delays messages exceeding the rate limit by up to
+	// RateLimiter.MaxDelay and drops messages, that would wait longer.
+	RateQueue
+)
+
+// RateLimiterStats holds the counters of a RateLimiter.
+type RateLimiterStats struct {
+	// Dispatched is the number of dispatched packets, including delayed
+	// packets.
+	Dispatched uint64
+	// Delayed is the number of delayed packets (RateQueue).
+	Delayed uint64
+	// Dropped is the number of dropped packets.
+	Dropped uint64
+	// Sources is the number of tracked source addresses.
+	Sources int
+}
+
+// tokenBucket holds the tokens of a RateLimit.
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// reserve takes n tokens and returns the time until they are available. If it
+// is longer than maxWait, no tokens are taken and false is returned.
+func (b *tokenBucket) reserve(limit RateLimit, n int, now time.Time, maxWait time.Duration) (time.Duration, bool) {
+	if limit.Rate <= 0 {
+		return 0, true
+	}
+	burst := float64(max(limit.Burst, 1))
+
+	if b.last.IsZero() {
+		b.tokens = burst
+	} else {
+		b.tokens = math.Min(burst, b.tokens+now.Sub(b.last).Seconds()*limit.Rate)
+	}
+	b.last = now
+
+	tokens := b.tokens - float64(n)
+	var wait time.Duration
+	if tokens < 0 {
+		wait = time.Duration(-tokens / limit.Rate * float64(time.Second))
+	}
+	if wait > maxWait {
+		return wait, false
+	}
+	b.tokens = tokens
+	return wait, true
+}
+
+// cancel returns n tokens taken by reserve.
+func (b *tokenBucket) cancel(limit RateLimit, n int) {
+	if limit.Rate > 0 {
+		b.tokens += float64(n)
+	}
+}
+
+// full returns true if the bucket is refilled at now.
+func (b *tokenBucket) full(limit RateLimit, now time.Time) bool {
+	if limit.Rate <= 0 {
+		return true
+	}
+	return b.tokens+now.Sub(b.last).Seconds()*limit.Rate >= float64(max(limit.Burst, 1))
+}
+
+// rateSource is the state of a source address.
+type rateSource struct {
+	ip      net.IP
+	bucket  tokenBucket
+	queue   []queuedPacket
+	running bool
+}
+
+// queuedPacket is a delayed packet.
+type queuedPacket struct {
+	packet Packet
+	addr   net.Addr
+	due    time.Time
+}
+
+// sourceLimit is the RateLimit of a network.
+type sourceLimit struct {
+	network *net.IPNet
+	limit   RateLimit
+}
+
+// addressLimit is the RateLimit of an OSC address pattern.
+type addressLimit struct {
+	regex  *regexp.Regexp
+	limit  RateLimit
+	bucket tokenBucket
+}
+
+// RateLimiter is a Dispatcher, that limits the rate of received messages per
+// source address and per OSC address pattern before they are dispatched by
+// Next. Every message of a bundle counts. The ports of the source addresses
+// are ignored.
+//
+// With RateQueue, delayed packets are dispatched in order by a goroutine of
+// the source, so handlers may be called concurrently for different sources.
+type RateLimiter struct {
+	// Next dispatches the packets within the rate limits.
+	Next Dispatcher
+	// PerSource is the rate limit of every source address without own limit
+	// (see LimitSource).
+	PerSource RateLimit
+	// Policy defines what happens to packets exceeding a rate limit.
+	Policy RatePolicy
+	// MaxDelay is the maximum time a packet is delayed with RateQueue.
+	MaxDelay time.Duration
+	// Throttled is called for every dropped packet.
+	Throttled func(packet Packet, addr net.Addr)
+
+	mutex         sync.Mutex
+	sources       map[string]*rateSource
+	sourceLimits  []sourceLimit
+	addressLimits []*addressLimit
+	lastPurge     time.Time
+	dispatched    atomic.Uint64
+	delayed       atomic.Uint64
+	dropped       atomic.Uint64
+}
+
+// NewRateLimiter returns a RateLimiter, that dispatches the packets within
+// the rate limit perSource of every source address with next.
+func NewRateLimiter(next Dispatcher, perSource RateLimit) *RateLimiter {
+	return &RateLimiter{Next: next, PerSource: perSource}
+}
+
+// LimitSource sets the rate limit of the source addresses in the network
+// source (CIDR notation or IP address). The first matching network is used.
+func (r *RateLimiter) LimitSource(source string, limit RateLimit) error {
+	network, err := parseNetwork(source)
+	if err != nil {
+		return err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.sourceLimits = append(r.sourceLimits, sourceLimit{network: network, limit: limit})
+	return nil
+}
+
+// LimitAddress sets the rate limit of all messages (of all sources) to the
+// OSC address pattern. Messages with an address pattern count for every
+// limited pattern.
+func (r *RateLimiter) LimitAddress(pattern string, limit RateLimit) error {
+	regex, err := getRegEx(pattern)
+	if err != nil {
+		return err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.addressLimits = append(r.addressLimits, &addressLimit{regex: regex, limit: limit})
+	return nil
+}
+
+// Stats returns the counters of the rate limiter.
+func (r *RateLimiter) Stats() RateLimiterStats {
+	r.mutex.Lock()
+	sources := len(r.sources)
+	r.mutex.Unlock()
+
+	return RateLimiterStats{
+		Dispatched: r.dispatched.Load(),
+		Delayed:    r.delayed.Load(),
+		Dropped:    r.dropped.Load(),
+		Sources:    sources,
+	}
+}
+
+// Dispatch implements the Dispatcher interface.
+func (r *RateLimiter) Dispatch(packet Packet, addr net.Addr) error {
+	msgs := messages(packet)
+	if len(msgs) == 0 {
+		return r.dispatch(packet, addr)
+	}
+
+	maxWait := time.Duration(0)
+	if r.Policy == RateQueue {
+		maxWait = r.MaxDelay
+	}
+	now := time.Now()
+
+	r.mutex.Lock()
+	r.purge(now)
+	src := r.source(addr)
+
+	wait, ok := r.reserve(src, msgs, now, maxWait)
+	if !ok {
+		r.mutex.Unlock()
+
+		r.dropped.Add(1)
+		if r.Throttled != nil {
+			r.Throttled(packet, addr)
+		}
+		return nil
+	}
+
+	// keep the order of delayed packets
+	if wait > 0 || src.running {
+		src.queue = append(src.queue, queuedPacket{packet: packet, addr: addr, due: now.Add(wait)})
+		if !src.running {
+			src.running = true
+			go r.drain(src)
+		}
+		r.mutex.Unlock()
+		r.delayed.Add(1)
+		return nil
+	}
+	r.mutex.Unlock()
+
+	return r.dispatch(packet, addr)
+}
+
+// reserve takes the tokens for msgs of src from all buckets and returns the
+// time until they are available. If it is longer than maxWait, no tokens are
+// taken and false is returned. The mutex must be locked.
+func (r *RateLimiter) reserve(src *rateSource, msgs []*Message, now time.Time, maxWait time.Duration) (time.Duration, bool) {
+	limit := r.sourceLimit(src.ip)
+	wait, ok := src.bucket.reserve(limit, len(msgs), now, maxWait)
+	if !ok {
+		return 0, false
+	}
+
+	type reservation struct {
+		limit *addressLimit
+		n     int
+	}
+	var reserved []reservation
+	for _, l := range r.addressLimits {
+		n := 0
+		for _, msg := range msgs {
+			if hasPattern(msg.Address) || l.regex.MatchString(msg.Address) {
+				n++
+			}
+		}
+		if n == 0 {
+			continue
+		}
+
+		w, ok := l.bucket.reserve(l.limit, n, now, maxWait)
+		if !ok {
+			src.bucket.cancel(limit, len(msgs))
+			for _, res := range reserved {
+				res.limit.bucket.cancel(res.limit.limit, res.n)
+			}
+			return 0, false
+		}
+		wait = max(wait, w)
+		reserved = append(reserved, reservation{limit: l, n: n})
+	}
+	return wait, true
+}
+
+// dispatch dispatches a packet with Next.
+func (r *RateLimiter) dispatch(packet Packet, addr net.Addr) error {
+	r.dispatched.Add(1)
+	if r.Next == nil {
+		return nil
+	}
+	return r.Next.Dispatch(packet, addr)
+}
+
+// drain dispatches the delayed packets of src.
+func (r *RateLimiter) drain(src *rateSource) {
+	for {
+		r.mutex.Lock()
+		if len(src.queue) == 0 {
+			src.running = false
+			r.mutex.Unlock()
+			return
+		}
+		q := src.queue[0]
+		src.queue = src.queue[1:]
+		r.mutex.Unlock()
+
+		time.Sleep(time.Until(q.due))
+		_ = r.dispatch(q.packet, q.addr)
+	}
+}
+
+// source returns the state of the source address addr. The mutex must be
+// locked.
+func (r *RateLimiter) source(addr net.Addr) *rateSource {
+	ip := addrIP(addr)
+	key := ""
+	if ip != nil {
+		key = ip.String()
+	} else if addr != nil {
+		key = addr.String()
+	}
+
+	if r.sources == nil {
+		r.sources = make(map[string]*rateSource)
+	}
+	src, ok := r.sources[key]
+	if !ok {
+		src = &rateSource{ip: ip}
+		r.sources[key] = src
+	}
+	return src
+}
+
+// sourceLimit returns the rate limit of the source address ip. The mutex must
+// be locked.
+func (r *RateLimiter) sourceLimit(ip net.IP) RateLimit {
+	if ip != nil {
+		for _, l := range r.sourceLimits {
+			if l.network.Contains(ip) {
+				return l.limit
+			}
+		}
+	}
+	return r.PerSource
+}
+
+// purge removes idle sources with full buckets. The mutex must be locked.
+func (r *RateLimiter) purge(now time.Time) {
+	if now.Sub(r.lastPurge) < rateLimitPurgeInterval {
+		return
+	}
+	r.lastPurge = now
+
+	for key, src := range r.sources {
+		if !src.running && src.bucket.full(r.sourceLimit(src.ip), now) {
+			delete(r.sources, key)
+		}
+	}
+}
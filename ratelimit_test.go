@@ -0,0 +1,121 @@
+package osc_test
+
+import (
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"bekuba.de/go-osc"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// recorder is a Dispatcher, that records the arguments of all messages.
+type recorder struct {
+	mutex sync.Mutex
+	args  []any
+}
+
+func (r *recorder) Dispatch(packet osc.Packet, addr net.Addr) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if msg, ok := packet.(*osc.Message); ok {
+		r.args = append(r.args, msg.Arguments...)
+	}
+	return nil
+}
+
+func (r *recorder) recorded() []any {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return append([]any(nil), r.args...)
+}
+
+func TestRateLimiter(t *testing.T) {
+	rec := &recorder{}
+	limiter := osc.NewRateLimiter(rec, osc.RateLimit{Rate: 10, Burst: 5})
+	throttled := 0
+	limiter.Throttled = func(packet osc.Packet, addr net.Addr) {
+		throttled++
+	}
+
+	flooder := &net.UDPAddr{IP: net.IPv4(10, 0, 0, 66), Port: 9000}
+	tablet := &net.UDPAddr{IP: net.IPv4(10, 0, 0, 2), Port: 9000}
+	console := &net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 9000}
+	assert.NoError(t, limiter.LimitSource("10.0.0.1", osc.RateLimit{}))
+
+	for i := int32(0); i < 20; i++ {
+		assert.NoError(t, limiter.Dispatch(osc.NewMessage("/fader", i), flooder))
+	}
+	// the flooder on another port is the same source
+	assert.NoError(t, limiter.Dispatch(osc.NewMessage("/fader", int32(20)), &net.UDPAddr{IP: flooder.IP, Port: 9001}))
+
+	// other sources aren't affected
+	assert.NoError(t, limiter.Dispatch(osc.NewMessage("/fader", int32(100)), tablet))
+	for i := int32(0); i < 20; i++ {
+		assert.NoError(t, limiter.Dispatch(osc.NewMessage("/cue", i), console))
+	}
+
+	assert.Len(t, rec.recorded(), 5+1+20)
+	assert.Equal(t, []any{int32(0), int32(1), int32(2), int32(3), int32(4), int32(100)}, rec.recorded()[:6])
+	assert.Equal(t, 16, throttled)
+	assert.Equal(t, osc.RateLimiterStats{Dispatched: 26, Dropped: 16, Sources: 3}, limiter.Stats())
+
+	// every message of a bundle counts
+	bundle := osc.NewBundle(time.Now())
+	for i := 0; i < 6; i++ {
+		assert.NoError(t, bundle.Append(osc.NewMessage("/fader")))
+	}
+	assert.NoError(t, limiter.Dispatch(bundle, &net.UDPAddr{IP: net.IPv4(10, 0, 0, 3)}))
+	assert.Equal(t, 17, throttled)
+
+	assert.ErrorIs(t, limiter.LimitSource("10.0.0", osc.RateLimit{}), osc.ErrorOscAddressFormat)
+}
+
+func TestRateLimiterAddress(t *testing.T) {
+	rec := &recorder{}
+	limiter := osc.NewRateLimiter(rec, osc.RateLimit{})
+	assert.NoError(t, limiter.LimitAddress("/meter/*", osc.RateLimit{Rate: 1, Burst: 2}))
+
+	a := &net.UDPAddr{IP: net.IPv4(10, 0, 0, 1)}
+	b := &net.UDPAddr{IP: net.IPv4(10, 0, 0, 2)}
+
+	// the limit is shared by all sources
+	assert.NoError(t, limiter.Dispatch(osc.NewMessage("/meter/1", int32(1)), a))
+	assert.NoError(t, limiter.Dispatch(osc.NewMessage("/meter/2", int32(2)), b))
+	assert.NoError(t, limiter.Dispatch(osc.NewMessage("/meter/1", int32(3)), a))
+	assert.NoError(t, limiter.Dispatch(osc.NewMessage("/fader", int32(4)), a))
+	// address patterns count for all limited addresses
+	assert.NoError(t, limiter.Dispatch(osc.NewMessage("/*", int32(5)), b))
+
+	assert.Equal(t, []any{int32(1), int32(2), int32(4)}, rec.recorded())
+	assert.Equal(t, uint64(2), limiter.Stats().Dropped)
+}
+
+func TestRateLimiterQueue(t *testing.T) {
+	rec := &recorder{}
+	limiter := osc.NewRateLimiter(rec, osc.RateLimit{Rate: 100, Burst: 1})
+	limiter.Policy = osc.RateQueue
+	limiter.MaxDelay = 55 * time.Millisecond
+
+	addr := &net.UDPAddr{IP: net.IPv4(10, 0, 0, 1)}
+	start := time.Now()
+	for i := int32(0); i < 10; i++ {
+		assert.NoError(t, limiter.Dispatch(osc.NewMessage("/fader", i), addr))
+	}
+	// Dispatch doesn't wait
+	assert.Less(t, time.Since(start), 10*time.Millisecond)
+
+	// one immediately, five delayed by 10 ms each, the others would wait too long
+	assert.Eventually(t, func() bool {
+		return len(rec.recorded()) == 6
+	}, time.Second, time.Millisecond)
+	assert.GreaterOrEqual(t, time.Since(start), 45*time.Millisecond)
+	assert.Equal(t, []any{int32(0), int32(1), int32(2), int32(3), int32(4), int32(5)}, rec.recorded())
+
+	stats := limiter.Stats()
+	assert.Equal(t, uint64(6), stats.Dispatched)
+	assert.Equal(t, uint64(5), stats.Delayed)
+	assert.Equal(t, uint64(4), stats.Dropped)
+}
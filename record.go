@@ -0,0 +1,383 @@
+package osc
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// recordingMagic starts every recording.
+const recordingMagic = "#oscrec\x00"
+
+// recordingVersion is the version of the recording format.
+const recordingVersion = 1
+
+// errIncompleteRecord is returned by readRecord for a record, that is cut off
+// by the end of the recording.
+var errIncompleteRecord = errors.New("incomplete record")
+
+// Record is a recorded OSC packet.
+type Record struct {
+	// Offset is the time since the start of the recording.
+	Offset time.Duration
+	// Addr is the source address.
+	Addr   net.Addr
+	Packet Packet
+}
+
+// RecordedAddr is a recorded source address of a network without own address
+// type (e.g. "tls").
+type RecordedAddr struct {
+	Net  string
+	Addr string
+}
+
+// Network implements the net.Addr interface.
+func (a *RecordedAddr) Network() string {
+	return a.Net
+}
+
+// String implements the net.Addr interface.
+func (a *RecordedAddr) String() string {
+	return a.Addr
+}
+
+// Recorder is a Dispatcher, that writes all packets with the time and the
+// source address to a recording (see NewPlayer) before they are dispatched by
+// Next. Write errors don't stop dispatching (see Err).
+//
+// A recording is a header followed by records, all integers are big-endian:
+//
+//	header: "#oscrec\x00", uint32 version (1), int64 start time (Unix
+//	        nanoseconds)
+//	record: int64 offset to the start time (nanoseconds),
+//	        uint16 length, network of the source address (e.g. "udp"),
+//	        uint16 length, source address (e.g. "10.0.0.1:9000"),
+//	        uint32 length, OSC packet
+//
+// The network and the address of an unknown source are empty.
+type Recorder struct {
+	// Next dispatches the recorded packets.
+	Next Dispatcher
+
+	mutex sync.Mutex
+	w     io.Writer
+	start time.Time
+	err   error
+}
+
+// NewRecorder writes the header of a recording to w and returns a Recorder,
+// that dispatches the recorded packets with next.
+func NewRecorder(w io.Writer, next Dispatcher) (*Recorder, error) {
+	r := &Recorder{Next: next, w: w, start: time.Now()}
+
+	header := []byte(recordingMagic)
+	header = binary.BigEndian.AppendUint32(header, recordingVersion)
+	header = binary.BigEndian.AppendUint64(header, uint64(r.start.UnixNano()))
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Record writes a packet from addr received now.
+func (r *Recorder) Record(packet Packet, addr net.Addr) error {
+	data, err := packet.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	var network, address string
+	if addr != nil {
+		network, address = addr.Network(), addr.String()
+	}
+	if len(network) > 0xffff || len(address) > 0xffff {
+		return fmt.Errorf("%w: source address too long", ErrorOscAddressFormat)
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	rec := binary.BigEndian.AppendUint64(nil, uint64(time.Since(r.start)))
+	rec = binary.BigEndian.AppendUint16(rec, uint16(len(network)))
+	rec = append(rec, network...)
+	rec = binary.BigEndian.AppendUint16(rec, uint16(len(address)))
+	rec = append(rec, address...)
+	rec = binary.BigEndian.AppendUint32(rec, uint32(len(data)))
+	rec = append(rec, data...)
+
+	// one write per record
+	_, err = r.w.Write(rec)
+	return err
+}
+
+// Err returns the first write error of Dispatch.
+func (r *Recorder) Err() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.err
+}
+
+// Dispatch implements the Dispatcher interface.
+func (r *Recorder) Dispatch(packet Packet, addr net.Addr) error {
+	if err := r.Record(packet, addr); err != nil {
+		r.mutex.Lock()
+		if r.err == nil {
+			r.err = err
+		}
+		r.mutex.Unlock()
+	}
+
+	if r.Next == nil {
+		return nil
+	}
+	return r.Next.Dispatch(packet, addr)
+}
+
+// Player plays a recording with the original timing, scaled or as fast as
+// possible, starting at the current position (see Seek). The fields must not
+// be changed while playing.
+type Player struct {
+	// Start is the start time of the recording.
+	Start time.Time
+	// Records are the recorded packets in order.
+	Records []Record
+	// Speed scales the timing, e.g. 2 plays twice as fast. Zero means real
+	// time, a negative speed plays as fast as possible.
+	Speed float64
+	// Loop restarts at the beginning after the last record.
+	Loop bool
+
+	mutex  sync.Mutex
+	pos    int
+	seeked bool
+}
+
+// NewPlayer reads a recording of a Recorder. An incomplete last record (e.g.
+// of an interrupted recording) is ignored. A record with a corrupt length,
+// that reaches beyond the end, returns ErrorInvalidRecording.
+func NewPlayer(r io.Reader) (*Player, error) {
+	header := make([]byte, len(recordingMagic)+12)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrorInvalidRecording, err)
+	}
+	if string(header[:len(recordingMagic)]) != recordingMagic {
+		return nil, fmt.Errorf("%w: not a recording", ErrorInvalidRecording)
+	}
+	header = header[len(recordingMagic):]
+	if version := binary.BigEndian.Uint32(header); version != recordingVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrorInvalidRecording, version)
+	}
+	p := &Player{Start: time.Unix(0, int64(binary.BigEndian.Uint64(header[4:])))}
+
+	for {
+		rec, err := readRecord(r)
+		if err == io.EOF || err == errIncompleteRecord {
+			return p, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		p.Records = append(p.Records, rec)
+	}
+}
+
+// readRecord reads the next record of a recording.
+func readRecord(r io.Reader) (Record, error) {
+	var rec Record
+
+	var offset int64
+	if err := binary.Read(r, binary.BigEndian, &offset); err == io.EOF {
+		return rec, err
+	} else if err != nil {
+		return rec, incompleteRecord(r, err)
+	}
+	rec.Offset = time.Duration(offset)
+
+	network, err := readRecordField(r, 2)
+	if err != nil {
+		return rec, err
+	}
+	address, err := readRecordField(r, 2)
+	if err != nil {
+		return rec, err
+	}
+	rec.Addr = recordedAddr(string(network), string(address))
+
+	data, err := readRecordField(r, 4)
+	if err != nil {
+		return rec, err
+	}
+	if rec.Packet, err = UnmarshalPacket(data); err != nil {
+		return rec, fmt.Errorf("%w: record at %v: %w", ErrorInvalidRecording, rec.Offset, err)
+	}
+	return rec, nil
+}
+
+// readRecordField reads a field with a length of size bytes.
+func readRecordField(r io.Reader, size int) ([]byte, error) {
+	buf := make([]byte, size)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, incompleteRecord(r, err)
+	}
+
+	var n uint32
+	if size == 2 {
+		n = uint32(binary.BigEndian.Uint16(buf))
+	} else {
+		n = binary.BigEndian.Uint32(buf)
+	}
+
+	var field bytes.Buffer
+	if _, err := io.CopyN(&field, r, int64(n)); err != nil {
+		// a recorded node doesn't send packets of this size, the length is
+		// corrupt
+		if err == io.EOF && n > DefaultMaxPacketSize {
+			return nil, fmt.Errorf("%w: invalid length %d", ErrorInvalidRecording, n)
+		}
+		return nil, incompleteRecord(r, err)
+	}
+	return field.Bytes(), nil
+}
+
+// incompleteRecord returns errIncompleteRecord for a short read, if r is at
+// the end, and other errors unchanged.
+func incompleteRecord(r io.Reader, err error) error {
+	if err != io.EOF && err != io.ErrUnexpectedEOF {
+		return err
+	}
+	var b [1]byte
+	if n, probeErr := r.Read(b[:]); n == 0 && probeErr == io.EOF {
+		return errIncompleteRecord
+	}
+	return fmt.Errorf("%w: %w", ErrorInvalidRecording, err)
+}
+
+// recordedAddr returns the address of network.
+func recordedAddr(network, address string) net.Addr {
+	switch {
+	case network == "":
+		return nil
+	case strings.HasPrefix(network, "udp"):
+		if addr, err := net.ResolveUDPAddr(network, address); err == nil {
+			return addr
+		}
+	case strings.HasPrefix(network, "unix"):
+		return &net.UnixAddr{Name: address, Net: network}
+	}
+	return &RecordedAddr{Net: network, Addr: address}
+}
+
+// Duration returns the time of the last record.
+func (p *Player) Duration() time.Duration {
+	if len(p.Records) == 0 {
+		return 0
+	}
+	return p.Records[len(p.Records)-1].Offset
+}
+
+// Seek sets the position to the first record at or after offset. It can be
+// called while playing.
+func (p *Player) Seek(offset time.Duration) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.pos = len(p.Records)
+	for i, rec := range p.Records {
+		if rec.Offset >= offset {
+			p.pos = i
+			break
+		}
+	}
+	p.seeked = true
+}
+
+// Position returns the offset of the next record.
+func (p *Player) Position() time.Duration {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.pos >= len(p.Records) {
+		return p.Duration()
+	}
+	return p.Records[p.pos].Offset
+}
+
+// Play dispatches the records with d and their source addresses until the end
+// of the recording or ctx is done.
+func (p *Player) Play(ctx context.Context, d Dispatcher) error {
+	return p.play(ctx, func(rec Record) error {
+		return d.Dispatch(rec.Packet, rec.Addr)
+	})
+}
+
+// Send sends the records with node to raddr until the end of the recording
+// or ctx is done.
+func (p *Player) Send(ctx context.Context, node *Node, raddr string) error {
+	addr, err := node.resolve(raddr)
+	if err != nil {
+		return err
+	}
+	return p.play(ctx, func(rec Record) error {
+		return node.SendToAddr(addr, rec.Packet)
+	})
+}
+
+// play calls f for every record in time.
+func (p *Player) play(ctx context.Context, f func(rec Record) error) error {
+	var start time.Time
+	var base time.Duration
+	rebase := true
+
+	for {
+		p.mutex.Lock()
+		if p.pos >= len(p.Records) {
+			if !p.Loop || len(p.Records) == 0 {
+				p.mutex.Unlock()
+				return nil
+			}
+			p.pos = 0
+			rebase = true
+		}
+		if p.seeked {
+			p.seeked = false
+			rebase = true
+		}
+		rec := p.Records[p.pos]
+		p.pos++
+		p.mutex.Unlock()
+
+		if rebase {
+			start, base = time.Now(), rec.Offset
+			rebase = false
+		}
+
+		if p.Speed >= 0 {
+			speed := p.Speed
+			if speed == 0 {
+				speed = 1
+			}
+			due := start.Add(time.Duration(float64(rec.Offset-base) / speed))
+			timer := time.NewTimer(time.Until(due))
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			}
+		} else if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if err := f(rec); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package osc_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"bekuba.de/go-osc"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecorder(t *testing.T) {
+	var buf bytes.Buffer
+	rec := &recorder{}
+	r, err := osc.NewRecorder(&buf, rec)
+	assert.NoError(t, err)
+
+	console := &net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 9000}
+	tls := &osc.TLSAddr{Addr: &net.TCPAddr{IP: net.IPv4(10, 0, 0, 2), Port: 443}}
+	bundle := osc.NewBundle(time.Now())
+	assert.NoError(t, bundle.Append(osc.NewMessage("/fader/1", float32(0.5))))
+
+	assert.NoError(t, r.Dispatch(osc.NewMessage("/cue/go", int32(1)), console))
+	time.Sleep(10 * time.Millisecond)
+	assert.NoError(t, r.Dispatch(bundle, tls))
+	assert.NoError(t, r.Dispatch(osc.NewMessage("/cue/go", int32(2)), nil))
+	assert.NoError(t, r.Err())
+	// dispatched by Next
+	assert.Equal(t, []any{int32(1), int32(2)}, rec.recorded())
+
+	p, err := osc.NewPlayer(bytes.NewReader(buf.Bytes()))
+	assert.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), p.Start, time.Second)
+	assert.Len(t, p.Records, 3)
+
+	assert.Equal(t, osc.NewMessage("/cue/go", int32(1)), p.Records[0].Packet)
+	assert.Equal(t, console.String(), p.Records[0].Addr.String())
+	assert.IsType(t, &net.UDPAddr{}, p.Records[0].Addr)
+
+	assert.Equal(t, bundle.Messages, p.Records[1].Packet.(*osc.Bundle).Messages)
+	assert.Equal(t, &osc.RecordedAddr{Net: "tls", Addr: "10.0.0.2:443"}, p.Records[1].Addr)
+	assert.GreaterOrEqual(t, p.Records[1].Offset-p.Records[0].Offset, 10*time.Millisecond)
+
+	assert.Nil(t, p.Records[2].Addr)
+	assert.Equal(t, p.Records[2].Offset, p.Duration())
+
+	// an incomplete last record is ignored
+	p, err = osc.NewPlayer(bytes.NewReader(buf.Bytes()[:buf.Len()-3]))
+	assert.NoError(t, err)
+	assert.Len(t, p.Records, 2)
+
+	// read errors aren't taken for the end of the recording
+	for _, n := range []int{buf.Len() - 3, buf.Len() - 30} {
+		readErr := errors.New("read error")
+		_, err = osc.NewPlayer(io.MultiReader(bytes.NewReader(buf.Bytes()[:n]), iotest.ErrReader(readErr)))
+		assert.ErrorIs(t, err, readErr)
+	}
+
+	// a short read is only the end, if the reader is at the end
+	_, err = osc.NewPlayer(io.MultiReader(bytes.NewReader(buf.Bytes()[:buf.Len()-3]), iotest.ErrReader(io.ErrUnexpectedEOF)))
+	assert.ErrorIs(t, err, osc.ErrorInvalidRecording)
+
+	_, err = osc.NewPlayer(bytes.NewReader([]byte("#bundle\x00")))
+	assert.ErrorIs(t, err, osc.ErrorInvalidRecording)
+}
+
+func TestPlayerCorruptRecord(t *testing.T) {
+	var buf bytes.Buffer
+	r, err := osc.NewRecorder(&buf, nil)
+	assert.NoError(t, err)
+
+	var starts []int
+	for i := int32(0); i < 3; i++ {
+		starts = append(starts, buf.Len())
+		assert.NoError(t, r.Record(osc.NewMessage("/cue/go", i), nil))
+	}
+
+	// the packet length of the second record reaches beyond the end
+	data := bytes.Clone(buf.Bytes())
+	binary.BigEndian.PutUint32(data[starts[1]+12:], 0x7fff0000)
+	_, err = osc.NewPlayer(bytes.NewReader(data))
+	assert.ErrorIs(t, err, osc.ErrorInvalidRecording)
+
+	p, err := osc.NewPlayer(bytes.NewReader(buf.Bytes()))
+	assert.NoError(t, err)
+	assert.Len(t, p.Records, 3)
+}
+
+// newRecording returns a player of messages with the arguments 0 to n-1 every
+// interval.
+func newRecording(n int, interval time.Duration) *osc.Player {
+	p := &osc.Player{}
+	for i := 0; i < n; i++ {
+		p.Records = append(p.Records, osc.Record{
+			Offset: time.Duration(i) * interval,
+			Addr:   &net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 9000},
+			Packet: osc.NewMessage("/fader/1", int32(i)),
+		})
+	}
+	return p
+}
+
+func TestPlayer(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("real time", func(t *testing.T) {
+		rec := &recorder{}
+		start := time.Now()
+		assert.NoError(t, newRecording(5, 10*time.Millisecond).Play(ctx, rec))
+		assert.GreaterOrEqual(t, time.Since(start), 40*time.Millisecond)
+		assert.Equal(t, []any{int32(0), int32(1), int32(2), int32(3), int32(4)}, rec.recorded())
+	})
+
+	t.Run("scaled", func(t *testing.T) {
+		p := newRecording(5, 20*time.Millisecond)
+		p.Speed = 4
+		start := time.Now()
+		assert.NoError(t, p.Play(ctx, &recorder{}))
+		assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+		assert.Less(t, time.Since(start), 60*time.Millisecond)
+	})
+
+	t.Run("as fast as possible", func(t *testing.T) {
+		p := newRecording(5, time.Hour)
+		p.Speed = -1
+		rec := &recorder{}
+		assert.NoError(t, p.Play(ctx, rec))
+		assert.Len(t, rec.recorded(), 5)
+	})
+
+	t.Run("seek", func(t *testing.T) {
+		p := newRecording(5, time.Hour)
+		p.Seek(150 * time.Minute)
+		assert.Equal(t, 3*time.Hour, p.Position())
+
+		// the first record after seeking is played immediately, the next one
+		// after 10 ms
+		p.Seek(3 * time.Hour)
+		p.Speed = 360000
+		rec := &recorder{}
+		start := time.Now()
+		assert.NoError(t, p.Play(ctx, rec))
+		assert.Less(t, time.Since(start), 100*time.Millisecond)
+		assert.Equal(t, []any{int32(3), int32(4)}, rec.recorded())
+		assert.Equal(t, 4*time.Hour, p.Position())
+	})
+
+	t.Run("loop", func(t *testing.T) {
+		p := newRecording(3, 0)
+		p.Loop = true
+		rec := &recorder{}
+		ctx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+		defer cancel()
+		assert.ErrorIs(t, p.Play(ctx, rec), context.DeadlineExceeded)
+		assert.Greater(t, len(rec.recorded()), 3)
+		assert.Equal(t, []any{int32(0), int32(1), int32(2), int32(0)}, rec.recorded()[:4])
+	})
+}
+
+func TestPlayerSend(t *testing.T) {
+	sender, err := osc.NewNode("127.0.0.1:0")
+	assert.NoError(t, err)
+	defer sender.Close()
+
+	node, received := newReceiver(t)
+
+	p := newRecording(3, time.Millisecond)
+	assert.NoError(t, p.Send(context.Background(), sender, node.Conn().LocalAddr().String()))
+	for i := int32(0); i < 3; i++ {
+		select {
+		case msg := <-received:
+			assert.Equal(t, osc.ArgumentsType{i}, msg.Arguments)
+		case <-time.After(time.Second):
+			t.Fatal("timeout")
+		}
+	}
+}
@@ -0,0 +1,287 @@
+package osc
+
+import (
+	"context"
+	"math/rand/v2"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultReliableTimeout is the time SendReliable waits for the first
+	// acknowledgement, if Node.ReliableTimeout is not set.
+	DefaultReliableTimeout = 100 * time.Millisecond
+	// DefaultReliableMaxTimeout is the maximum time SendReliable waits for an
+	// acknowledgement, if Node.ReliableMaxTimeout is not set.
+	DefaultReliableMaxTimeout = 2 * time.Second
+	// DefaultReliableRetries is the number of times SendReliable sends a
+	// packet again, if Node.ReliableRetries is not set.
+	DefaultReliableRetries = 8
+
+	// reliableAddress is the address of the sequence number message.
+	reliableAddress = "/reliable"
+	// reliableAckAddress is the address of acknowledgements.
+	reliableAckAddress = "/reliable/ack"
+	// reliableDuplicateWindow is the time received sequence numbers are
+	// remembered to suppress duplicates.
+	reliableDuplicateWindow = 30 * time.Second
+	// reliableMaxSources is the maximum number of sources, whose sequence
+	// numbers are remembered.
+	reliableMaxSources = 1024
+)
+
+// SendReliable sends an OSC Bundle or an OSC Message to raddr and waits until
+// raddr acknowledges it. The packet is sent again with exponential backoff
+// (see ReliableTimeout) up to ReliableRetries times, then
+// ErrorNotAcknowledged is returned. If ctx is done before, the error of ctx is
+// returned.
+//
+// The packet is wrapped into a bundle with an immediate timetag, that
+// contains the message "/reliable ,h <sequence number>" and the packet. A
+// receiving node with AcceptReliable set answers with
+// "/reliable/ack ,h <sequence number>", drops duplicates and dispatches the
+// packet only.
+//
+// The node must be serving (ListenAndServe) to receive the acknowledgement.
+// Don't call SendReliable in a handler of the same node.
+func (sc *Node) SendReliable(ctx context.Context, raddr string, packet Packet) error {
+	addr, err := sc.resolve(raddr)
+	if err != nil {
+		return err
+	}
+
+	acked := make(chan struct{})
+	seq := sc.reliable.add(addr, acked)
+	defer sc.reliable.remove(seq)
+
+	bundle := &Bundle{Timetag: NewImmediateTimetag()}
+	bundle.Messages = append(bundle.Messages, NewMessage(reliableAddress, int64(seq)))
+	if err := bundle.Append(packet); err != nil {
+		return err
+	}
+	data, err := bundle.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	timeout := sc.ReliableTimeout
+	if timeout <= 0 {
+		timeout = DefaultReliableTimeout
+	}
+	maxTimeout := sc.ReliableMaxTimeout
+	if maxTimeout <= 0 {
+		maxTimeout = DefaultReliableMaxTimeout
+	}
+	retries := sc.ReliableRetries
+	if retries == 0 {
+		retries = DefaultReliableRetries
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for attempt := 0; ; attempt++ {
+		if err := sc.writeTo(data, addr); err != nil {
+			return err
+		}
+
+		select {
+		case <-acked:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+			if attempt >= retries {
+				return ErrorNotAcknowledged
+			}
+			timeout = min(2*timeout, maxTimeout)
+			timer.Reset(timeout)
+		}
+	}
+}
+
+// receiveReliable returns nil for the acknowledgement of a pending reliable
+// packet. If AcceptReliable is set, it acknowledges a received reliable packet
+// and returns its payload, or nil if the packet is a duplicate. Other packets
+// are returned unchanged.
+func (sc *Node) receiveReliable(packet Packet, addr net.Addr) Packet {
+	if msg, ok := packet.(*Message); ok && msg.Address == reliableAckAddress {
+		if seq, ok := reliableSeq(msg); ok && sc.reliable.ack(seq, addr) {
+			return nil
+		}
+		return packet
+	}
+	if !sc.AcceptReliable {
+		return packet
+	}
+
+	bundle, ok := packet.(*Bundle)
+	if !ok || len(bundle.Messages) == 0 || bundle.Messages[0].Address != reliableAddress {
+		return packet
+	}
+	seq, ok := reliableSeq(bundle.Messages[0])
+	if !ok {
+		return packet
+	}
+
+	var payload Packet
+	switch {
+	case len(bundle.Messages) == 2 && len(bundle.Bundles) == 0:
+		payload = bundle.Messages[1]
+	case len(bundle.Messages) == 1 && len(bundle.Bundles) == 1:
+		payload = bundle.Bundles[0]
+	default:
+		return packet
+	}
+
+	if data, err := NewMessage(reliableAckAddress, int64(seq)).MarshalBinary(); err == nil {
+		_ = sc.writeTo(data, addr)
+	}
+	if sc.reliable.duplicate(seq, addr) {
+		return nil
+	}
+	return payload
+}
+
+// reliableSeq returns the sequence number of a reliable or an acknowledgement
+// message.
+func reliableSeq(msg *Message) (uint64, bool) {
+	if len(msg.Arguments) != 1 {
+		return 0, false
+	}
+	seq, ok := msg.Arguments[0].(int64)
+	return uint64(seq), ok
+}
+
+// pendingReliable is a reliable packet waiting for its acknowledgement.
+type pendingReliable struct {
+	addr  net.Addr
+	acked chan struct{}
+}
+
+// receivedReliable is the window of the sequence numbers received from a
+// source: the highest sequence number and a bit for each of the 64 sequence
+// numbers before.
+type receivedReliable struct {
+	max  uint64
+	mask uint64
+	last time.Time
+}
+
+// reliableState holds the pending and received reliable packets of a node.
+// The zero value has no packets.
+type reliableState struct {
+	mutex     sync.Mutex
+	seq       uint64
+	pending   map[uint64]*pendingReliable
+	received  map[string]*receivedReliable
+	lastPurge time.Time
+}
+
+// add adds a pending packet to addr and returns its sequence number. The
+// sequence numbers start randomly, so a restarted node isn't taken for a
+// duplicate.
+func (s *reliableState) add(addr net.Addr, acked chan struct{}) uint64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.pending == nil {
+		s.pending = make(map[uint64]*pendingReliable)
+		s.seq = rand.Uint64()
+	}
+	s.seq++
+	s.pending[s.seq] = &pendingReliable{addr: addr, acked: acked}
+	return s.seq
+}
+
+// remove removes the pending packet seq.
+func (s *reliableState) remove(seq uint64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.pending, seq)
+}
+
+// ack marks the pending packet seq as acknowledged by addr and returns true,
+// if it is pending.
+func (s *reliableState) ack(seq uint64, addr net.Addr) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	p, ok := s.pending[seq]
+	if !ok || !sameAddr(p.addr, addr) {
+		return false
+	}
+	close(p.acked)
+	delete(s.pending, seq)
+	return true
+}
+
+// duplicate records the received packet seq of addr and returns true if it
+// was received before. The 64 sequence numbers before the highest received
+// one are remembered per source. A sequence number further behind starts a
+// new window, like the random first sequence number of a restarted node.
+// Sources are forgotten after reliableDuplicateWindow, or the least recently
+// seen one if there are more than reliableMaxSources.
+func (s *reliableState) duplicate(seq uint64, addr net.Addr) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now()
+	if now.Sub(s.lastPurge) >= reliableDuplicateWindow {
+		s.lastPurge = now
+		for key, r := range s.received {
+			if now.Sub(r.last) >= reliableDuplicateWindow {
+				delete(s.received, key)
+			}
+		}
+	}
+
+	if s.received == nil {
+		s.received = make(map[string]*receivedReliable)
+	}
+	var key string
+	if addr != nil {
+		key = addr.Network() + " " + addr.String()
+	}
+	r, ok := s.received[key]
+	if !ok {
+		if len(s.received) >= reliableMaxSources {
+			s.evict()
+		}
+		s.received[key] = &receivedReliable{max: seq, mask: 1, last: now}
+		return false
+	}
+	r.last = now
+
+	switch diff := seq - r.max; {
+	case diff == 0:
+		return true
+	case diff < 64:
+		// newer than the highest sequence number
+		r.max, r.mask = seq, r.mask<<diff|1
+		return false
+	case -diff < 64:
+		bit := uint64(1) << -diff
+		if r.mask&bit != 0 {
+			return true
+		}
+		r.mask |= bit
+		return false
+	default:
+		r.max, r.mask = seq, 1
+		return false
+	}
+}
+
+// evict removes the least recently seen source.
+func (s *reliableState) evict() {
+	var oldest string
+	var last time.Time
+	for key, r := range s.received {
+		if last.IsZero() || r.last.Before(last) {
+			oldest, last = key, r.last
+		}
+	}
+	delete(s.received, oldest)
+}
@@ -0,0 +1,252 @@
+package osc_test
+
+import (
+	"context"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"bekuba.de/go-osc"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// lossyNet is an in-memory network, that drops the packets accepted by drop.
+type lossyNet struct {
+	mutex sync.Mutex
+	conns map[string]*lossyConn
+	drop  func(from, to net.Addr) bool
+	sent  atomic.Int32
+}
+
+// listen returns a connection with the local address addr.
+func (n *lossyNet) listen(addr string) *lossyConn {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	udpAddr, _ := net.ResolveUDPAddr("udp", addr)
+	c := &lossyConn{net: n, addr: udpAddr, packets: make(chan lossyPacket, 100), closed: make(chan struct{})}
+	if n.conns == nil {
+		n.conns = make(map[string]*lossyConn)
+	}
+	n.conns[udpAddr.String()] = c
+	return c
+}
+
+type lossyPacket struct {
+	data []byte
+	from net.Addr
+}
+
+// lossyConn is a net.PacketConn of a lossyNet.
+type lossyConn struct {
+	net     *lossyNet
+	addr    *net.UDPAddr
+	packets chan lossyPacket
+	closed  chan struct{}
+	once    sync.Once
+}
+
+func (c *lossyConn) ReadFrom(p []byte) (int, net.Addr, error) {
+	select {
+	case packet := <-c.packets:
+		return copy(p, packet.data), packet.from, nil
+	case <-c.closed:
+		return 0, nil, net.ErrClosed
+	}
+}
+
+func (c *lossyConn) WriteTo(p []byte, addr net.Addr) (int, error) {
+	n := c.net
+	n.sent.Add(1)
+
+	n.mutex.Lock()
+	dest, ok := n.conns[addr.String()]
+	drop := n.drop != nil && n.drop(c.addr, addr)
+	n.mutex.Unlock()
+
+	if ok && !drop {
+		select {
+		case dest.packets <- lossyPacket{data: append([]byte(nil), p...), from: c.addr}:
+		default:
+		}
+	}
+	return len(p), nil
+}
+
+func (c *lossyConn) Close() error {
+	c.once.Do(func() { close(c.closed) })
+	return nil
+}
+
+func (c *lossyConn) LocalAddr() net.Addr                { return c.addr }
+func (c *lossyConn) SetDeadline(t time.Time) error      { return nil }
+func (c *lossyConn) SetReadDeadline(t time.Time) error  { return nil }
+func (c *lossyConn) SetWriteDeadline(t time.Time) error { return nil }
+
+func TestSendReliable(t *testing.T) {
+	lossy := &lossyNet{}
+	var sent, acks atomic.Int32
+	lossy.drop = func(from, to net.Addr) bool {
+		// drop the first packet and the first acknowledgement
+		if from.String() == "10.0.0.1:8000" {
+			return sent.Add(1) == 1
+		}
+		return acks.Add(1) == 1
+	}
+
+	sender := osc.NewNodeFromConn(lossy.listen("10.0.0.1:8000"))
+	sender.ReliableTimeout = 10 * time.Millisecond
+	defer sender.Close()
+	go sender.ListenAndServe(nil)
+
+	receiver := osc.NewNodeFromConn(lossy.listen("10.0.0.2:8000"))
+	receiver.AcceptReliable = true
+	defer receiver.Close()
+	d, received := newMessageReceiver(t)
+	go receiver.ListenAndServe(d)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.NoError(t, sender.SendReliable(ctx, "10.0.0.2:8000", osc.NewMessage("/cue/go", int32(1))))
+	// lost, duplicate with lost acknowledgement, acknowledged duplicate
+	assert.Equal(t, int32(3), sent.Load())
+
+	// dispatched once
+	select {
+	case msg := <-received:
+		assert.Equal(t, osc.NewMessage("/cue/go", int32(1)), msg)
+	case <-time.After(time.Second):
+		t.Fatal("timeout")
+	}
+	select {
+	case msg := <-received:
+		t.Fatalf("unexpected message %v", msg)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestSendReliableTimeout(t *testing.T) {
+	lossy := &lossyNet{drop: func(from, to net.Addr) bool { return true }}
+
+	sender := osc.NewNodeFromConn(lossy.listen("10.0.0.1:8000"))
+	sender.ReliableTimeout = 20 * time.Millisecond
+	defer sender.Close()
+	go sender.ListenAndServe(nil)
+	lossy.listen("10.0.0.2:8000")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	err := sender.SendReliable(ctx, "10.0.0.2:8000", osc.NewMessage("/cue/go"))
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// sent after 0, 20, 60 and 140 ms
+	assert.Equal(t, int32(4), lossy.sent.Load())
+}
+
+func TestSendReliableUDP(t *testing.T) {
+	sender, err := osc.NewNode("127.0.0.1:0")
+	assert.NoError(t, err)
+	defer sender.Close()
+	go sender.ListenAndServe(nil)
+
+	received := make(packetReceiver, 10)
+	receiver := newServingNode(t, received, func(node *osc.Node) {
+		node.AcceptReliable = true
+	})
+
+	bundle := osc.NewBundle(time.Now())
+	assert.NoError(t, bundle.Append(osc.NewMessage("/cue/go", int32(1))))
+	assert.NoError(t, bundle.Append(osc.NewMessage("/cue/go", int32(2))))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.NoError(t, sender.SendReliable(ctx, receiver.Conn().LocalAddr().String(), bundle))
+
+	select {
+	case packet := <-received:
+		assert.Equal(t, bundle.Messages, packet.(*osc.Bundle).Messages)
+	case <-time.After(time.Second):
+		t.Fatal("timeout")
+	}
+}
+
+func TestSendReliableRetries(t *testing.T) {
+	lossy := &lossyNet{}
+
+	sender := osc.NewNodeFromConn(lossy.listen("10.0.0.1:8000"))
+	sender.ReliableTimeout = 5 * time.Millisecond
+	sender.ReliableRetries = 2
+	defer sender.Close()
+	go sender.ListenAndServe(nil)
+
+	// the receiver doesn't accept reliable packets
+	receiver := osc.NewNodeFromConn(lossy.listen("10.0.0.2:8000"))
+	defer receiver.Close()
+	received := make(packetReceiver, 10)
+	go receiver.ListenAndServe(received)
+
+	err := sender.SendReliable(context.Background(), "10.0.0.2:8000", osc.NewMessage("/cue/go"))
+	assert.ErrorIs(t, err, osc.ErrorNotAcknowledged)
+	assert.Equal(t, int32(3), lossy.sent.Load())
+
+	// the wrapping bundle is dispatched unchanged
+	select {
+	case packet := <-received:
+		bundle := packet.(*osc.Bundle)
+		if assert.Len(t, bundle.Messages, 2) {
+			assert.Equal(t, "/reliable", bundle.Messages[0].Address)
+			assert.Equal(t, osc.NewMessage("/cue/go"), bundle.Messages[1])
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout")
+	}
+}
+
+func TestAcceptReliableDuplicates(t *testing.T) {
+	lossy := &lossyNet{}
+
+	sender := osc.NewNodeFromConn(lossy.listen("10.0.0.1:8000"))
+	defer sender.Close()
+	acks := make(packetReceiver, 10)
+	go sender.ListenAndServe(acks)
+
+	receiver := osc.NewNodeFromConn(lossy.listen("10.0.0.2:8000"))
+	receiver.AcceptReliable = true
+	defer receiver.Close()
+	received := make(packetReceiver, 10)
+	go receiver.ListenAndServe(received)
+
+	// 20 is too far behind and starts a new window
+	for _, seq := range []int64{100, 100, 99, 101, 99, 20, 20} {
+		bundle := osc.NewBundle(time.Now())
+		bundle.Timetag = osc.NewImmediateTimetag()
+		assert.NoError(t, bundle.Append(osc.NewMessage("/reliable", seq)))
+		assert.NoError(t, bundle.Append(osc.NewMessage("/cue/go", seq)))
+		assert.NoError(t, sender.SendTo("10.0.0.2:8000", bundle))
+
+		// all packets are acknowledged
+		select {
+		case packet := <-acks:
+			assert.Equal(t, osc.NewMessage("/reliable/ack", seq), packet)
+		case <-time.After(time.Second):
+			t.Fatal("timeout")
+		}
+	}
+
+	for _, seq := range []int64{100, 99, 101, 20} {
+		select {
+		case packet := <-received:
+			assert.Equal(t, osc.NewMessage("/cue/go", seq), packet)
+		case <-time.After(time.Second):
+			t.Fatal("timeout")
+		}
+	}
+	select {
+	case packet := <-received:
+		t.Fatalf("unexpected packet %v", packet)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
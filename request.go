@@ -0,0 +1,128 @@
+package osc
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+)
+
+// Request sends the OSC Message msg to raddr and waits for the first reply of
+// raddr with the same OSC address as msg. If match is not nil, the reply must
+// also be accepted by match, e.g. to check the arguments.
+//
+// The node must be serving (ListenAndServe) to receive the reply. Replies are
+// dispatched as usual. Don't call Request in a handler of the same node, the
+// reply can't be received while the handler is running.
+//
+// If RequestTimeout is set, msg is sent again up to RequestRetries times, if
+// no reply is received in time, and ErrorRequestTimeout is returned at last.
+// Otherwise Request waits until ctx is done.
+func (sc *Node) Request(ctx context.Context, raddr string, msg *Message, match func(msg *Message) bool) (*Message, error) {
+	addr, err := sc.resolve(raddr)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &pendingRequest{
+		addr:    addr,
+		address: msg.Address,
+		match:   match,
+		reply:   make(chan *Message, 1),
+	}
+	id := sc.requests.add(r)
+	defer sc.requests.remove(id)
+
+	var timeout <-chan time.Time
+	var timer *time.Timer
+	if sc.RequestTimeout > 0 {
+		timer = time.NewTimer(sc.RequestTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	for attempt := 0; ; attempt++ {
+		if err := sc.SendToAddr(addr, msg); err != nil {
+			return nil, err
+		}
+		if timer != nil {
+			timer.Reset(sc.RequestTimeout)
+		}
+
+		select {
+		case reply := <-r.reply:
+			return reply, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-timeout:
+			if attempt >= sc.RequestRetries {
+				return nil, ErrorRequestTimeout
+			}
+		}
+	}
+}
+
+// pendingRequest is a request waiting for its reply.
+type pendingRequest struct {
+	addr    net.Addr
+	address string
+	match   func(msg *Message) bool
+	reply   chan *Message
+}
+
+// accepts returns true if msg from addr is a reply to the request.
+func (r *pendingRequest) accepts(msg *Message, addr net.Addr) bool {
+	if msg.Address != r.address || !sameAddr(r.addr, addr) {
+		return false
+	}
+	return r.match == nil || r.match(msg)
+}
+
+// requestTable holds all pending requests of a node. The zero value is an
+// empty table.
+type requestTable struct {
+	mutex   sync.Mutex
+	nextID  uint64
+	pending map[uint64]*pendingRequest
+}
+
+// add adds a pending request and returns its id.
+func (t *requestTable) add(r *pendingRequest) uint64 {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.pending == nil {
+		t.pending = make(map[uint64]*pendingRequest)
+	}
+	t.nextID++
+	t.pending[t.nextID] = r
+	return t.nextID
+}
+
+// remove removes the pending request with id.
+func (t *requestTable) remove(id uint64) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	delete(t.pending, id)
+}
+
+// deliver passes all messages of a received packet to the pending requests.
+// Every request gets the first reply only.
+func (t *requestTable) deliver(packet Packet, addr net.Addr) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if len(t.pending) == 0 {
+		return
+	}
+
+	for _, msg := range messages(packet) {
+		for id, r := range t.pending {
+			if r.accepts(msg, addr) {
+				r.reply <- msg
+				delete(t.pending, id)
+			}
+		}
+	}
+}
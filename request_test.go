@@ -0,0 +1,108 @@
+package osc_test
+
+import (
+	"context"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"bekuba.de/go-osc"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newEchoServer returns the address of a node, that replies to every message
+// with the same message. The first `drop` messages are not answered.
+func newEchoServer(t *testing.T, drop int32) string {
+	var server *osc.Node
+	var count atomic.Int32
+	d := osc.NewStandardDispatcher()
+	err := d.AddMsgHandlerExt("*", func(msg *osc.Message, addr net.Addr) {
+		if count.Add(1) <= drop {
+			return
+		}
+		err := server.SendTo(addr.String(), msg)
+		assert.NoError(t, err)
+	})
+	assert.NoError(t, err)
+	server = newServingNode(t, d, nil)
+
+	return server.Conn().LocalAddr().String()
+}
+
+func TestRequest(t *testing.T) {
+	addr := newEchoServer(t, 0)
+
+	client, err := osc.NewNode("127.0.0.1:0")
+	assert.NoError(t, err)
+	defer client.Close()
+
+	d, dispatched := newMessageReceiver(t)
+	go client.ListenAndServe(d)
+
+	t.Run("should return reply", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		reply, err := client.Request(ctx, addr, osc.NewMessage("/xinfo", "test"), nil)
+		assert.NoError(t, err)
+		assert.Equal(t, osc.NewMessage("/xinfo", "test"), reply)
+
+		// replies are dispatched as usual
+		assert.Equal(t, reply, <-dispatched)
+	})
+
+	t.Run("should handle concurrent requests", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		wait := sync.WaitGroup{}
+		for i := int32(0); i < 10; i++ {
+			wait.Add(1)
+			go func() {
+				defer wait.Done()
+				match := func(msg *osc.Message) bool {
+					v, err := msg.Arguments.Int32(0)
+					return err == nil && v == i
+				}
+				reply, err := client.Request(ctx, addr, osc.NewMessage("/ch/01/mix/fader", i), match)
+				assert.NoError(t, err)
+				assert.Equal(t, osc.NewMessage("/ch/01/mix/fader", i), reply)
+			}()
+		}
+		wait.Wait()
+	})
+
+	t.Run("should fail if context is done", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		_, err := client.Request(ctx, addr, osc.NewMessage("/status"), func(msg *osc.Message) bool {
+			return false
+		})
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
+
+func TestRequestRetries(t *testing.T) {
+	addr := newEchoServer(t, 2)
+
+	client, err := osc.NewNode("127.0.0.1:0")
+	assert.NoError(t, err)
+	defer client.Close()
+	go client.ListenAndServe(nil)
+
+	client.RequestTimeout = 50 * time.Millisecond
+	client.RequestRetries = 1
+
+	// 2 requests are dropped
+	_, err = client.Request(context.Background(), addr, osc.NewMessage("/status"), nil)
+	assert.ErrorIs(t, err, osc.ErrorRequestTimeout)
+
+	// the first request is answered
+	reply, err := client.Request(context.Background(), addr, osc.NewMessage("/status"), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, osc.NewMessage("/status"), reply)
+}
@@ -0,0 +1,279 @@
+package osc
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// DefaultSendQueueCapacity is the number of packets a SendQueue holds per
+// destination, if SendQueue.Capacity is not set.
+const DefaultSendQueueCapacity = 256
+
+// DefaultSendQueueIdleTimeout is the time the goroutine of an idle destination
+// waits for new packets, if SendQueue.IdleTimeout is not set.
+const DefaultSendQueueIdleTimeout = 10 * time.Second
+
+// QueuePolicy defines what happens to packets sent to a full SendQueue.
+type QueuePolicy int
+
+const (
+	// QueueBlock blocks the sender until there is space in the queue.
+	QueueBlock QueuePolicy = iota
+	// QueueDrop drops the packet and returns ErrorQueueFull.
+	QueueDrop
+)
+
+// SendQueueStats holds the counters of a SendQueue.
+type SendQueueStats struct {
+	// Sent is the number of sent packets.
+	Sent uint64
+	// Dropped is the number of packets dropped because of a full queue.
+	Dropped uint64
+	// Failed is the number of packets, that couldn't be sent.
+	Failed uint64
+	// Queued is the number of packets waiting to be sent.
+	Queued int
+	// Destinations is the number of destinations with a queue.
+	Destinations int
+}
+
+// sendQueueDest is the queue of a destination.
+type sendQueueDest struct {
+	key     string
+	addr    net.Addr
+	packets [][]byte
+	// cond signals new packets and free space, it uses the mutex of the
+	// SendQueue
+	cond *sync.Cond
+}
+
+// SendQueue sends packets asynchronously with a node. Every destination has
+// its own bounded queue and goroutine, that sends the packets in order with a
+// gap of at least Gap between two packets, e.g. for devices dropping packets
+// arriving too fast. The goroutine stops after IdleTimeout without packets.
+type SendQueue struct {
+	// Gap is the minimum time between two packets to the same destination.
+	Gap time.Duration
+	// Capacity is the maximum number of queued packets per destination. Zero
+	// means DefaultSendQueueCapacity.
+	Capacity int
+	// Policy defines what happens to packets sent to a full queue.
+	Policy QueuePolicy
+	// IdleTimeout is the time after the last packet, the queue of a
+	// destination is removed. Zero means DefaultSendQueueIdleTimeout, it is at
+	// least Gap.
+	IdleTimeout time.Duration
+	// SendFailed is called, if sending a queued packet fails.
+	SendFailed func(addr net.Addr, err error)
+
+	node    *Node
+	mutex   sync.Mutex
+	idle    *sync.Cond
+	dests   map[string]*sendQueueDest
+	queued  int
+	sending int
+	closed  bool
+	wg      sync.WaitGroup
+	stats   SendQueueStats
+}
+
+// NewSendQueue returns a SendQueue, that sends with node and waits at least
+// gap between two packets to the same destination.
+func NewSendQueue(node *Node, gap time.Duration) *SendQueue {
+	q := &SendQueue{Gap: gap, node: node, dests: make(map[string]*sendQueueDest)}
+	q.idle = sync.NewCond(&q.mutex)
+	return q
+}
+
+// SendTo queues an OSC Bundle or an OSC Message to raddr. If the queue of
+// raddr is full, SendTo blocks or returns ErrorQueueFull (see Policy).
+func (q *SendQueue) SendTo(raddr string, packet Packet) error {
+	addr, err := q.node.resolve(raddr)
+	if err != nil {
+		return err
+	}
+	return q.SendToAddr(addr, packet)
+}
+
+// SendToAddr queues an OSC Bundle or an OSC Message to addr (see SendTo). If
+// the queue of addr was removed after IdleTimeout while the sender was
+// blocked, a new queue is created.
+func (q *SendQueue) SendToAddr(addr net.Addr, packet Packet) error {
+	data, err := packet.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if q.closed {
+		return ErrorNodeClosed
+	}
+	dest := q.dest(addr)
+
+	for len(dest.packets) >= q.capacity() {
+		if q.Policy == QueueDrop {
+			q.stats.Dropped++
+			return ErrorQueueFull
+		}
+		dest.cond.Wait()
+		if q.closed {
+			return ErrorNodeClosed
+		}
+		// the queue may have been removed after the idle timeout
+		dest = q.dest(addr)
+	}
+
+	dest.packets = append(dest.packets, data)
+	q.queued++
+	dest.cond.Broadcast()
+	return nil
+}
+
+// SendMsgTo queues an OSC Message to raddr (all int types converted to int32,
+// see Node.SendMsgTo).
+func (q *SendQueue) SendMsgTo(raddr string, path string, args ...any) error {
+	msg, err := newMessageFromArgs(path, args...)
+	if err != nil {
+		return err
+	}
+	return q.SendTo(raddr, msg)
+}
+
+// capacity returns the capacity of a queue.
+func (q *SendQueue) capacity() int {
+	if q.Capacity <= 0 {
+		return DefaultSendQueueCapacity
+	}
+	return q.Capacity
+}
+
+// idleTimeout returns the time an idle queue is kept.
+func (q *SendQueue) idleTimeout() time.Duration {
+	timeout := q.IdleTimeout
+	if timeout <= 0 {
+		timeout = DefaultSendQueueIdleTimeout
+	}
+	return max(timeout, q.Gap)
+}
+
+// dest returns the queue of addr and starts its goroutine. The mutex must be
+// locked.
+func (q *SendQueue) dest(addr net.Addr) *sendQueueDest {
+	key := addr.Network() + " " + addr.String()
+	dest, ok := q.dests[key]
+	if !ok {
+		dest = &sendQueueDest{key: key, addr: addr, cond: sync.NewCond(&q.mutex)}
+		q.dests[key] = dest
+		q.wg.Add(1)
+		go q.run(dest)
+	}
+	return dest
+}
+
+// run sends the packets of dest until the queue is closed and empty, or idle
+// for the idle timeout.
+func (q *SendQueue) run(dest *sendQueueDest) {
+	defer q.wg.Done()
+
+	// timer wakes up the goroutine to check the idle timeout
+	timer := time.AfterFunc(q.idleTimeout(), func() {
+		q.mutex.Lock()
+		dest.cond.Broadcast()
+		q.mutex.Unlock()
+	})
+	defer timer.Stop()
+
+	var last time.Time
+	active := time.Now()
+	for {
+		q.mutex.Lock()
+		for len(dest.packets) == 0 && !q.closed {
+			idle := q.idleTimeout() - time.Since(active)
+			if idle <= 0 {
+				delete(q.dests, dest.key)
+				q.mutex.Unlock()
+				return
+			}
+			timer.Reset(idle)
+			dest.cond.Wait()
+		}
+		if len(dest.packets) == 0 {
+			q.mutex.Unlock()
+			return
+		}
+		data := dest.packets[0]
+		dest.packets = dest.packets[1:]
+		q.queued--
+		q.sending++
+		// wake up blocked senders
+		dest.cond.Broadcast()
+		q.mutex.Unlock()
+
+		if wait := time.Until(last.Add(q.Gap)); wait > 0 {
+			time.Sleep(wait)
+		}
+		err := q.node.writeTo(data, dest.addr)
+		last = time.Now()
+		active = last
+
+		q.mutex.Lock()
+		q.sending--
+		if err != nil {
+			q.stats.Failed++
+		} else {
+			q.stats.Sent++
+		}
+		if q.queued == 0 && q.sending == 0 {
+			q.idle.Broadcast()
+		}
+		q.mutex.Unlock()
+
+		if err != nil && q.SendFailed != nil {
+			q.SendFailed(dest.addr, err)
+		}
+	}
+}
+
+// Flush waits until all queued packets are sent.
+func (q *SendQueue) Flush() {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	for q.queued > 0 || q.sending > 0 {
+		q.idle.Wait()
+	}
+}
+
+// Stats returns the counters of the queue.
+func (q *SendQueue) Stats() SendQueueStats {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	stats := q.stats
+	stats.Queued = q.queued
+	stats.Destinations = len(q.dests)
+	return stats
+}
+
+// Close sends all queued packets and stops the queue. Blocked senders return
+// ErrorNodeClosed. If packets were dropped or couldn't be sent,
+// ErrorPacketsLost is returned. The node isn't closed.
+func (q *SendQueue) Close() error {
+	q.mutex.Lock()
+	q.closed = true
+	for _, dest := range q.dests {
+		dest.cond.Broadcast()
+	}
+	q.mutex.Unlock()
+
+	q.wg.Wait()
+
+	stats := q.Stats()
+	if stats.Dropped > 0 || stats.Failed > 0 {
+		return fmt.Errorf("%w: %d dropped, %d failed", ErrorPacketsLost, stats.Dropped, stats.Failed)
+	}
+	return nil
+}
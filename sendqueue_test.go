@@ -0,0 +1,118 @@
+package osc_test
+
+import (
+	"testing"
+	"time"
+
+	"bekuba.de/go-osc"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSendQueue(t *testing.T) {
+	sender, err := osc.NewNode("127.0.0.1:0")
+	assert.NoError(t, err)
+	defer sender.Close()
+
+	node, received := newReceiver(t)
+	addr := node.Conn().LocalAddr().String()
+
+	q := osc.NewSendQueue(sender, 5*time.Millisecond)
+	q.Capacity = 2
+
+	start := time.Now()
+	for i := int32(0); i < 10; i++ {
+		// blocks while the queue is full
+		assert.NoError(t, q.SendMsgTo(addr, "/fader/1", i))
+	}
+	assert.GreaterOrEqual(t, time.Since(start), 25*time.Millisecond)
+
+	q.Flush()
+	assert.GreaterOrEqual(t, time.Since(start), 45*time.Millisecond)
+	assert.Equal(t, osc.SendQueueStats{Sent: 10, Destinations: 1}, q.Stats())
+
+	for i := int32(0); i < 10; i++ {
+		select {
+		case msg := <-received:
+			assert.Equal(t, osc.ArgumentsType{i}, msg.Arguments)
+		case <-time.After(time.Second):
+			t.Fatal("timeout")
+		}
+	}
+
+	assert.NoError(t, q.Close())
+	assert.ErrorIs(t, q.SendMsgTo(addr, "/fader/1", int32(0)), osc.ErrorNodeClosed)
+}
+
+func TestSendQueueDrop(t *testing.T) {
+	sender, err := osc.NewNode("127.0.0.1:0")
+	assert.NoError(t, err)
+	defer sender.Close()
+
+	node, received := newReceiver(t)
+	addr := node.Conn().LocalAddr().String()
+
+	q := osc.NewSendQueue(sender, 100*time.Millisecond)
+	q.Capacity = 2
+	q.Policy = osc.QueueDrop
+
+	// the first packet is sent immediately, the second waits for the gap
+	assert.NoError(t, q.SendMsgTo(addr, "/fader/1", int32(1)))
+	assert.Eventually(t, func() bool { return q.Stats().Sent == 1 }, time.Second, time.Millisecond)
+	assert.NoError(t, q.SendMsgTo(addr, "/fader/1", int32(2)))
+	assert.Eventually(t, func() bool { return q.Stats().Queued == 0 }, time.Second, time.Millisecond)
+
+	assert.NoError(t, q.SendMsgTo(addr, "/fader/1", int32(3)))
+	assert.NoError(t, q.SendMsgTo(addr, "/fader/1", int32(4)))
+	assert.ErrorIs(t, q.SendMsgTo(addr, "/fader/1", int32(5)), osc.ErrorQueueFull)
+	assert.Equal(t, osc.SendQueueStats{Sent: 1, Dropped: 1, Queued: 2, Destinations: 1}, q.Stats())
+
+	// queued packets are sent on close, the dropped one is reported
+	assert.ErrorIs(t, q.Close(), osc.ErrorPacketsLost)
+	for i := int32(1); i <= 4; i++ {
+		select {
+		case msg := <-received:
+			assert.Equal(t, osc.ArgumentsType{i}, msg.Arguments)
+		case <-time.After(time.Second):
+			t.Fatal("timeout")
+		}
+	}
+	assert.Equal(t, uint64(4), q.Stats().Sent)
+}
+
+func TestSendQueueIdle(t *testing.T) {
+	sender, err := osc.NewNode("127.0.0.1:0")
+	assert.NoError(t, err)
+
+	node, received := newReceiver(t)
+	addr := node.Conn().LocalAddr().String()
+
+	q := osc.NewSendQueue(sender, 0)
+	q.IdleTimeout = 20 * time.Millisecond
+
+	assert.NoError(t, q.SendMsgTo(addr, "/fader/1", int32(1)))
+	q.Flush()
+	assert.Equal(t, 1, q.Stats().Destinations)
+
+	// the queue of an idle destination is removed and created again
+	assert.Eventually(t, func() bool { return q.Stats().Destinations == 0 }, time.Second, time.Millisecond)
+	assert.NoError(t, q.SendMsgTo(addr, "/fader/1", int32(2)))
+	q.Flush()
+	assert.Equal(t, osc.SendQueueStats{Sent: 2, Destinations: 1}, q.Stats())
+
+	for i := int32(1); i <= 2; i++ {
+		select {
+		case msg := <-received:
+			assert.Equal(t, osc.ArgumentsType{i}, msg.Arguments)
+		case <-time.After(time.Second):
+			t.Fatal("timeout")
+		}
+	}
+
+	// failed packets are reported
+	sender.Close()
+	assert.NoError(t, q.SendMsgTo(addr, "/fader/1", int32(3)))
+	err = q.Close()
+	assert.ErrorIs(t, err, osc.ErrorPacketsLost)
+	assert.Equal(t, uint64(1), q.Stats().Failed)
+}
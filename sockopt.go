@@ -0,0 +1,120 @@
+//go:build unix || windows
+
+package osc
+
+import (
+	"net"
+	"syscall"
+)
+
+// control calls f with the file descriptor of conn.
+func control(conn syscall.Conn, f func(fd sockFD) error) error {
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+	return rawControl(raw, f)
+}
+
+// rawControl calls f with the file descriptor of raw.
+func rawControl(raw syscall.RawConn, f func(fd sockFD) error) error {
+	var sockErr error
+	err := raw.Control(func(fd uintptr) {
+		sockErr = f(sockFD(fd))
+	})
+	if err != nil {
+		return err
+	}
+	return sockErr
+}
+
+// setSockoptInt sets an integer socket option of conn.
+func setSockoptInt(conn syscall.Conn, level, opt, value int) error {
+	return control(conn, func(fd sockFD) error {
+		return syscall.SetsockoptInt(fd, level, opt, value)
+	})
+}
+
+// setDualStack disables the IPV6_V6ONLY socket option of an IPv6 socket, the
+// socket receives IPv4 packets too.
+func setDualStack(raw syscall.RawConn) error {
+	return rawControl(raw, func(fd sockFD) error {
+		return syscall.SetsockoptInt(fd, syscall.IPPROTO_IPV6, syscall.IPV6_V6ONLY, 0)
+	})
+}
+
+// setBroadcast sets the SO_BROADCAST socket option of conn.
+func setBroadcast(conn syscall.Conn, enable bool) error {
+	return setSockoptInt(conn, syscall.SOL_SOCKET, syscall.SO_BROADCAST, boolToInt(enable))
+}
+
+// setMembership joins (or leaves) the multicast group on the interface ifi.
+// If ifi is nil, the system default interface is used.
+func setMembership(conn syscall.Conn, group net.IP, ifi *net.Interface, join bool) error {
+	if ip4 := group.To4(); ip4 != nil {
+		mreq := &syscall.IPMreq{}
+		copy(mreq.Multiaddr[:], ip4)
+		if ifi != nil {
+			addr, err := interfaceIPv4(ifi)
+			if err != nil {
+				return err
+			}
+			copy(mreq.Interface[:], addr)
+		}
+
+		opt := syscall.IP_ADD_MEMBERSHIP
+		if !join {
+			opt = syscall.IP_DROP_MEMBERSHIP
+		}
+		return control(conn, func(fd sockFD) error {
+			return syscall.SetsockoptIPMreq(fd, syscall.IPPROTO_IP, opt, mreq)
+		})
+	}
+
+	mreq := &syscall.IPv6Mreq{}
+	copy(mreq.Multiaddr[:], group.To16())
+	if ifi != nil {
+		mreq.Interface = uint32(ifi.Index)
+	}
+
+	opt := syscall.IPV6_JOIN_GROUP
+	if !join {
+		opt = syscall.IPV6_LEAVE_GROUP
+	}
+	return control(conn, func(fd sockFD) error {
+		return syscall.SetsockoptIPv6Mreq(fd, syscall.IPPROTO_IPV6, opt, mreq)
+	})
+}
+
+// setMulticastTTL sets the TTL (hop limit) of sent multicast packets.
+func setMulticastTTL(conn syscall.Conn, ipv6 bool, ttl int) error {
+	if ipv6 {
+		return setSockoptInt(conn, syscall.IPPROTO_IPV6, syscall.IPV6_MULTICAST_HOPS, ttl)
+	}
+	return setSockoptInt(conn, syscall.IPPROTO_IP, syscall.IP_MULTICAST_TTL, ttl)
+}
+
+// setMulticastLoopback enables or disables receiving of sent multicast
+// packets on the local host.
+func setMulticastLoopback(conn syscall.Conn, ipv6 bool, enable bool) error {
+	if ipv6 {
+		return setSockoptInt(conn, syscall.IPPROTO_IPV6, syscall.IPV6_MULTICAST_LOOP, boolToInt(enable))
+	}
+	return setSockoptInt(conn, syscall.IPPROTO_IP, syscall.IP_MULTICAST_LOOP, boolToInt(enable))
+}
+
+// interfaceIPv4 returns the first IPv4 address of the interface ifi.
+func interfaceIPv4(ifi *net.Interface) (net.IP, error) {
+	addrs, err := ifi.Addrs()
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range addrs {
+		if ipNet, ok := a.(*net.IPNet); ok {
+			if ip4 := ipNet.IP.To4(); ip4 != nil {
+				return ip4, nil
+			}
+		}
+	}
+	return nil, ErrorOscAddress
+}
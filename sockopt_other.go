@@ -0,0 +1,33 @@
+//go:build !unix && !windows
+
+package osc
+
+import (
+	"net"
+	"syscall"
+)
+
+// setDualStack isn't supported on this platform.
+func setDualStack(raw syscall.RawConn) error {
+	return ErrorNotSupported
+}
+
+// setBroadcast isn't supported on this platform.
+func setBroadcast(conn syscall.Conn, enable bool) error {
+	return ErrorNotSupported
+}
+
+// setMembership isn't supported on this platform.
+func setMembership(conn syscall.Conn, group net.IP, ifi *net.Interface, join bool) error {
+	return ErrorNotSupported
+}
+
+// setMulticastTTL isn't supported on this platform.
+func setMulticastTTL(conn syscall.Conn, ipv6 bool, ttl int) error {
+	return ErrorNotSupported
+}
+
+// setMulticastLoopback isn't supported on this platform.
+func setMulticastLoopback(conn syscall.Conn, ipv6 bool, enable bool) error {
+	return ErrorNotSupported
+}
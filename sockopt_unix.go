@@ -0,0 +1,6 @@
+//go:build unix
+
+package osc
+
+// sockFD is the type of a socket file descriptor.
+type sockFD = int
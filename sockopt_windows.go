@@ -0,0 +1,8 @@
+//go:build windows
+
+package osc
+
+import "syscall"
+
+// sockFD is the type of a socket file descriptor.
+type sockFD = syscall.Handle
@@ -0,0 +1,449 @@
+package osc
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+)
+
+// Framing is the framing of OSC packets on stream transports (TCP, TLS).
+type Framing int
+
+const (
+	// FramingLengthPrefix prepends the size of every packet as int32 (OSC 1.0).
+	FramingLengthPrefix Framing = iota
+	// FramingSLIP encodes every packet with double END SLIP (RFC 1055, OSC
+	// 1.1).
+	FramingSLIP
+)
+
+// SLIP special characters
+const (
+	slipEnd    = 0xc0
+	slipEsc    = 0xdb
+	slipEscEnd = 0xdc
+	slipEscEsc = 0xdd
+)
+
+// StreamConn is a stream connection (TCP or TLS), that carries framed OSC
+// packets.
+type StreamConn struct {
+	// MaxPacketSize is the maximum size of sent and received OSC packets in
+	// bytes. Zero means DefaultMaxPacketSize.
+	MaxPacketSize int
+	// DecodeOptions controls how received OSC packets are decoded.
+	DecodeOptions DecodeOptions
+
+	conn    net.Conn
+	reader  *bufio.Reader
+	framing Framing
+	addr    net.Addr
+
+	writeMutex sync.Mutex
+	closed     atomic.Bool
+}
+
+// NewStreamConn returns a StreamConn, that sends and receives OSC packets on
+// conn with framing.
+func NewStreamConn(conn net.Conn, framing Framing) *StreamConn {
+	return &StreamConn{
+		conn:    conn,
+		reader:  bufio.NewReader(conn),
+		framing: framing,
+		addr:    conn.RemoteAddr(),
+	}
+}
+
+// DialTCP opens a TCP connection to raddr.
+func DialTCP(raddr string, framing Framing) (*StreamConn, error) {
+	conn, err := net.Dial("tcp", raddr)
+	if err != nil {
+		return nil, err
+	}
+	return NewStreamConn(conn, framing), nil
+}
+
+// Send sends an OSC Bundle or an OSC Message.
+func (c *StreamConn) Send(packet Packet) error {
+	data, err := packet.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	return c.write(data)
+}
+
+// SendMsg sends an OSC Message (all int types converted to int32, see
+// SendMsgTo).
+func (c *StreamConn) SendMsg(path string, args ...any) error {
+	msg, err := newMessageFromArgs(path, args...)
+	if err != nil {
+		return err
+	}
+	return c.Send(msg)
+}
+
+// write writes a marshaled OSC packet with the framing of the connection.
+func (c *StreamConn) write(data []byte) error {
+	if c.closed.Load() {
+		return ErrorNodeClosed
+	}
+	if len(data) > c.maxPacketSize() {
+		return fmt.Errorf("%w: %d bytes (max. %d bytes)", ErrorPacketTooLarge, len(data), c.maxPacketSize())
+	}
+
+	var frame []byte
+	switch c.framing {
+	case FramingSLIP:
+		frame = slipEncode(data)
+	default:
+		frame = binary.BigEndian.AppendUint32(make([]byte, 0, 4+len(data)), uint32(len(data)))
+		frame = append(frame, data...)
+	}
+
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+	_, err := c.conn.Write(frame)
+	return err
+}
+
+// slipEncode returns data encoded with double END SLIP.
+func slipEncode(data []byte) []byte {
+	frame := make([]byte, 0, len(data)+2)
+	frame = append(frame, slipEnd)
+	for _, b := range data {
+		switch b {
+		case slipEnd:
+			frame = append(frame, slipEsc, slipEscEnd)
+		case slipEsc:
+			frame = append(frame, slipEsc, slipEscEsc)
+		default:
+			frame = append(frame, b)
+		}
+	}
+	return append(frame, slipEnd)
+}
+
+// Read retrieves the next OSC packet. If the peer closes the connection,
+// io.EOF is returned. Packets, that exceed MaxPacketSize, are skipped and
+// ErrorPacketTooLarge is returned.
+func (c *StreamConn) Read() (Packet, error) {
+	var data []byte
+	var err error
+	switch c.framing {
+	case FramingSLIP:
+		data, err = c.readSLIP()
+	default:
+		data, err = c.readLengthPrefix()
+	}
+	if err != nil {
+		if c.closed.Load() {
+			return nil, io.EOF
+		}
+		return nil, err
+	}
+	return c.DecodeOptions.UnmarshalPacket(data)
+}
+
+// readLengthPrefix reads a packet with int32 size prefix.
+func (c *StreamConn) readLengthPrefix() ([]byte, error) {
+	var size [4]byte
+	if _, err := io.ReadFull(c.reader, size[:]); err != nil {
+		return nil, err
+	}
+	n := int32(binary.BigEndian.Uint32(size[:]))
+	if n < 0 {
+		return nil, fmt.Errorf("%w: negative packet size %d", ErrorInvalidPacked, n)
+	}
+	if int(n) > c.maxPacketSize() {
+		if _, err := io.CopyN(io.Discard, c.reader, int64(n)); err != nil {
+			return nil, err
+		}
+		return nil, ErrorPacketTooLarge
+	}
+
+	data := make([]byte, n)
+	if _, err := io.ReadFull(c.reader, data); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return data, nil
+}
+
+// readSLIP reads a SLIP encoded packet. Empty packets are skipped.
+func (c *StreamConn) readSLIP() ([]byte, error) {
+	var data []byte
+	tooLarge := false
+	escaped := false
+
+	for {
+		b, err := c.reader.ReadByte()
+		if err != nil {
+			if errors.Is(err, io.EOF) && len(data) > 0 {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+
+		switch {
+		case b == slipEnd:
+			if tooLarge {
+				return nil, ErrorPacketTooLarge
+			}
+			if len(data) > 0 {
+				return data, nil
+			}
+			escaped = false
+			continue
+		case escaped:
+			escaped = false
+			switch b {
+			case slipEscEnd:
+				b = slipEnd
+			case slipEscEsc:
+				b = slipEsc
+			}
+		case b == slipEsc:
+			escaped = true
+			continue
+		}
+
+		if len(data) >= c.maxPacketSize() {
+			tooLarge = true
+			data = data[:0]
+		}
+		if !tooLarge {
+			data = append(data, b)
+		}
+	}
+}
+
+// Serve retrieves OSC packets and dispatches them with the address of the
+// peer until the connection is closed. Packets, that are too large or can't be
+// decoded, are dropped.
+func (c *StreamConn) Serve(d Dispatcher) error {
+	for {
+		packet, err := c.Read()
+		var decodeErr *DecodeError
+		if errors.Is(err, ErrorPacketTooLarge) || errors.As(err, &decodeErr) {
+			continue
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if d != nil {
+			if err := d.Dispatch(packet, c.addr); err != nil {
+				c.Close()
+				return err
+			}
+		}
+	}
+}
+
+// RemoteAddr returns the address of the peer, a *TLSAddr for TLS connections
+// accepted by a StreamServer.
+func (c *StreamConn) RemoteAddr() net.Addr {
+	return c.addr
+}
+
+// LocalAddr returns the local address.
+func (c *StreamConn) LocalAddr() net.Addr {
+	return c.conn.LocalAddr()
+}
+
+// Close closes the connection.
+func (c *StreamConn) Close() error {
+	if c.closed.Swap(true) {
+		return nil
+	}
+	return c.conn.Close()
+}
+
+// maxPacketSize returns the maximum packet size of the connection.
+func (c *StreamConn) maxPacketSize() int {
+	if c.MaxPacketSize > 0 {
+		return c.MaxPacketSize
+	}
+	return DefaultMaxPacketSize
+}
+
+// StreamServer accepts stream connections (TCP or TLS) and dispatches the
+// received OSC packets.
+type StreamServer struct {
+	// Dispatcher dispatches the packets of all connections.
+	Dispatcher Dispatcher
+	// Framing is the framing of all connections.
+	Framing Framing
+	// MaxPacketSize is the maximum size of sent and received OSC packets in
+	// bytes. Zero means DefaultMaxPacketSize.
+	MaxPacketSize int
+	// DecodeOptions controls how received OSC packets are decoded.
+	DecodeOptions DecodeOptions
+
+	mutex     sync.Mutex
+	listeners map[net.Listener]struct{}
+	conns     map[*StreamConn]struct{}
+	closed    bool
+}
+
+// NewStreamServer returns a StreamServer, that dispatches the received packets
+// with d.
+func NewStreamServer(d Dispatcher, framing Framing) *StreamServer {
+	return &StreamServer{Dispatcher: d, Framing: framing}
+}
+
+// ListenAndServe listens on the TCP address laddr and serves all connections.
+func (s *StreamServer) ListenAndServe(laddr string) error {
+	l, err := net.Listen("tcp", laddr)
+	if err != nil {
+		return ErrorOscAddress
+	}
+	return s.Serve(l)
+}
+
+// Serve accepts connections on l and serves them until the server is closed.
+func (s *StreamServer) Serve(l net.Listener) error {
+	s.mutex.Lock()
+	if s.closed {
+		s.mutex.Unlock()
+		l.Close()
+		return ErrorNodeClosed
+	}
+	if s.listeners == nil {
+		s.listeners = make(map[net.Listener]struct{})
+	}
+	s.listeners[l] = struct{}{}
+	s.mutex.Unlock()
+
+	defer func() {
+		s.mutex.Lock()
+		delete(s.listeners, l)
+		s.mutex.Unlock()
+		l.Close()
+	}()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			s.mutex.Lock()
+			closed := s.closed
+			s.mutex.Unlock()
+			if closed {
+				return nil
+			}
+			return err
+		}
+		go s.serveConn(conn)
+	}
+}
+
+// serveConn serves one connection until it is closed.
+func (s *StreamServer) serveConn(conn net.Conn) {
+	c := NewStreamConn(conn, s.Framing)
+	c.MaxPacketSize = s.MaxPacketSize
+	c.DecodeOptions = s.DecodeOptions
+
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		addr, err := tlsHandshake(tlsConn)
+		if err != nil {
+			conn.Close()
+			return
+		}
+		c.addr = addr
+	}
+
+	s.mutex.Lock()
+	if s.closed {
+		s.mutex.Unlock()
+		conn.Close()
+		return
+	}
+	if s.conns == nil {
+		s.conns = make(map[*StreamConn]struct{})
+	}
+	s.conns[c] = struct{}{}
+	s.mutex.Unlock()
+
+	defer func() {
+		s.mutex.Lock()
+		delete(s.conns, c)
+		s.mutex.Unlock()
+		c.Close()
+	}()
+
+	_ = c.Serve(s.Dispatcher)
+}
+
+// Conns returns all open connections.
+func (s *StreamServer) Conns() []*StreamConn {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	conns := make([]*StreamConn, 0, len(s.conns))
+	for c := range s.conns {
+		conns = append(conns, c)
+	}
+	return conns
+}
+
+// SendTo sends an OSC Bundle or an OSC Message to the connection with the
+// address addr.
+func (s *StreamServer) SendTo(addr net.Addr, packet Packet) error {
+	for _, c := range s.Conns() {
+		if sameAddr(c.addr, addr) {
+			return c.Send(packet)
+		}
+	}
+	return ErrorOscAddress
+}
+
+// SendToAll sends an OSC Bundle or an OSC Message to all connections. The
+// packet is marshaled once. If sending fails for some connections, SendErrors
+// is returned.
+func (s *StreamServer) SendToAll(packet Packet) error {
+	data, err := packet.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	var errs SendErrors
+	for _, c := range s.Conns() {
+		if err := c.write(data); err != nil {
+			errs = append(errs, &DestinationError{Addr: c.addr, Err: err})
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// Close closes all listeners and connections.
+func (s *StreamServer) Close() {
+	s.mutex.Lock()
+	s.closed = true
+	listeners := make([]net.Listener, 0, len(s.listeners))
+	for l := range s.listeners {
+		listeners = append(listeners, l)
+	}
+	s.mutex.Unlock()
+
+	for _, l := range listeners {
+		l.Close()
+	}
+	for _, c := range s.Conns() {
+		c.Close()
+	}
+}
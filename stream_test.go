@@ -0,0 +1,142 @@
+package osc_test
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"bekuba.de/go-osc"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStream(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		framing osc.Framing
+	}{
+		{"length prefix", osc.FramingLengthPrefix},
+		{"SLIP", osc.FramingSLIP},
+	} {
+		framing := tt.framing
+		t.Run(tt.name, func(t *testing.T) {
+			d := osc.NewStandardDispatcher()
+			server, addr := newStreamServer(t, d, framing)
+			err := d.AddMsgHandlerExt(ping, func(msg *osc.Message, addr net.Addr) {
+				err := server.SendTo(addr, osc.NewMessage(pong, msg.Arguments...))
+				assert.NoError(t, err)
+			})
+			assert.NoError(t, err)
+
+			client, err := osc.DialTCP(addr, framing)
+			assert.NoError(t, err)
+			defer client.Close()
+
+			// SLIP special characters in the packet
+			blob := []byte{0xc0, 0xdb, 0xdc, 0xdd, 0xc0}
+			for i := int32(0); i < 3; i++ {
+				err = client.SendMsg(ping, i, blob)
+				assert.NoError(t, err)
+			}
+			for i := int32(0); i < 3; i++ {
+				p, err := client.Read()
+				assert.NoError(t, err)
+				assert.Equal(t, osc.NewMessage(pong, i, blob), p)
+			}
+
+			// broadcast
+			err = server.SendToAll(osc.NewMessage("/meter", float32(0.5)))
+			assert.NoError(t, err)
+			p, err := client.Read()
+			assert.NoError(t, err)
+			assert.Equal(t, osc.NewMessage("/meter", float32(0.5)), p)
+
+			// closed by the server
+			server.Close()
+			_, err = client.Read()
+			assert.ErrorIs(t, err, io.EOF)
+		})
+	}
+}
+
+func TestStreamMaxPacketSize(t *testing.T) {
+	for _, framing := range []osc.Framing{osc.FramingLengthPrefix, osc.FramingSLIP} {
+		server, client := net.Pipe()
+		c1 := osc.NewStreamConn(server, framing)
+		c1.MaxPacketSize = 64
+		c2 := osc.NewStreamConn(client, framing)
+
+		err := c1.Send(osc.NewMessage("/blob", make([]byte, 64)))
+		assert.ErrorIs(t, err, osc.ErrorPacketTooLarge)
+
+		go func() {
+			assert.NoError(t, c2.Send(osc.NewMessage("/blob", make([]byte, 64))))
+			assert.NoError(t, c2.Send(osc.NewMessage("/blob", make([]byte, 8))))
+			c2.Close()
+		}()
+
+		// the large packet is skipped
+		_, err = c1.Read()
+		assert.ErrorIs(t, err, osc.ErrorPacketTooLarge)
+		p, err := c1.Read()
+		assert.NoError(t, err)
+		assert.Equal(t, osc.NewMessage("/blob", make([]byte, 8)), p)
+		_, err = c1.Read()
+		assert.ErrorIs(t, err, io.EOF)
+		c1.Close()
+	}
+}
+
+func TestStreamLengthPrefix(t *testing.T) {
+	server, client := net.Pipe()
+	c := osc.NewStreamConn(server, osc.FramingLengthPrefix)
+	defer c.Close()
+
+	data, err := osc.NewMessage(ping).MarshalBinary()
+	assert.NoError(t, err)
+
+	go func() {
+		frame := binary.BigEndian.AppendUint32(nil, uint32(len(data)))
+		_, _ = client.Write(append(frame, data...))
+		_, _ = client.Write([]byte{0xff, 0xff, 0xff, 0xf0})
+	}()
+
+	p, err := c.Read()
+	assert.NoError(t, err)
+	assert.Equal(t, osc.NewMessage(ping), p)
+
+	_, err = c.Read()
+	assert.ErrorIs(t, err, osc.ErrorInvalidPacked)
+	client.Close()
+}
+
+func TestStreamServerClose(t *testing.T) {
+	server := osc.NewStreamServer(nil, osc.FramingSLIP)
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	done := make(chan error)
+	go func() {
+		done <- server.Serve(l)
+	}()
+
+	client, err := osc.DialTCP(l.Addr().String(), osc.FramingSLIP)
+	assert.NoError(t, err)
+	defer client.Close()
+	assert.Eventually(t, func() bool {
+		return len(server.Conns()) == 1
+	}, time.Second, time.Millisecond)
+
+	server.Close()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("timeout")
+	}
+	assert.Eventually(t, func() bool {
+		return len(server.Conns()) == 0
+	}, time.Second, time.Millisecond)
+}
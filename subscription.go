@@ -0,0 +1,165 @@
+package osc
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+)
+
+// DefaultSubscriptionInterval is the renewal interval of a subscription, if
+// SubscriptionOptions.Interval is not set. Behringer X32/XAir consoles stop
+// sending updates 10 seconds after the last /xremote.
+const DefaultSubscriptionInterval = 5 * time.Second
+
+// SubscriptionOptions configures a Subscription.
+type SubscriptionOptions struct {
+	// Interval is the interval for sending the renewal message. Zero means
+	// DefaultSubscriptionInterval.
+	Interval time.Duration
+
+	// Timeout is the time without any packet of the peer, after which the
+	// peer is disconnected and the renewal message is sent again at once.
+	// Zero means two times Interval.
+	Timeout time.Duration
+
+	// OnConnected is called, when the first packet of the peer is received
+	// after subscribing or after a timeout.
+	OnConnected func(addr net.Addr)
+
+	// OnDisconnected is called, if the peer is silent for Timeout.
+	OnDisconnected func(addr net.Addr)
+}
+
+// Subscription periodically sends a renewal message (e.g. /xremote or
+// /subscribe) to a peer and watches if the peer is sending.
+type Subscription struct {
+	node *Node
+	addr net.Addr
+	msg  *Message
+	opts SubscriptionOptions
+
+	mutex     sync.Mutex
+	lastSeen  time.Time
+	connected bool
+
+	seen chan struct{}
+	done chan struct{}
+}
+
+// Subscribe sends the renewal message msg to raddr every opts.Interval until
+// ctx is done. The node must be serving (ListenAndServe) to detect if the
+// peer is sending. All packets of the peer are dispatched as usual.
+func (sc *Node) Subscribe(ctx context.Context, raddr string, msg *Message, opts SubscriptionOptions) (*Subscription, error) {
+	addr, err := sc.resolve(raddr)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultSubscriptionInterval
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 2 * opts.Interval
+	}
+
+	s := &Subscription{
+		node: sc,
+		addr: addr,
+		msg:  msg,
+		opts: opts,
+		seen: make(chan struct{}, 1),
+		done: make(chan struct{}),
+	}
+
+	remove := sc.watchers.add(s.receive)
+	go func() {
+		defer close(s.done)
+		defer remove()
+		s.run(ctx)
+	}()
+
+	return s, nil
+}
+
+// Connected returns true if the peer is sending.
+func (s *Subscription) Connected() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.connected
+}
+
+// LastSeen returns the time of the last received packet of the peer.
+func (s *Subscription) LastSeen() time.Time {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.lastSeen
+}
+
+// Done returns a channel that is closed, when the subscription is stopped.
+func (s *Subscription) Done() <-chan struct{} {
+	return s.done
+}
+
+// receive is called for every packet received by the node.
+func (s *Subscription) receive(packet Packet, addr net.Addr) {
+	if !sameAddr(s.addr, addr) {
+		return
+	}
+
+	s.mutex.Lock()
+	s.lastSeen = time.Now()
+	s.mutex.Unlock()
+
+	select {
+	case s.seen <- struct{}{}:
+	default:
+	}
+}
+
+// run sends the renewal messages and checks the connection until ctx is done.
+func (s *Subscription) run(ctx context.Context) {
+	renew := time.NewTicker(s.opts.Interval)
+	defer renew.Stop()
+
+	timeout := time.NewTimer(s.opts.Timeout)
+	defer timeout.Stop()
+
+	_ = s.node.SendToAddr(s.addr, s.msg)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-renew.C:
+			_ = s.node.SendToAddr(s.addr, s.msg)
+
+		case <-s.seen:
+			timeout.Reset(s.opts.Timeout)
+			if s.setConnected(true) && s.opts.OnConnected != nil {
+				s.opts.OnConnected(s.addr)
+			}
+
+		case <-timeout.C:
+			timeout.Reset(s.opts.Timeout)
+			if s.setConnected(false) && s.opts.OnDisconnected != nil {
+				s.opts.OnDisconnected(s.addr)
+			}
+			// subscribe again
+			_ = s.node.SendToAddr(s.addr, s.msg)
+		}
+	}
+}
+
+// setConnected sets the connection state and returns true if it's changed.
+func (s *Subscription) setConnected(connected bool) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	changed := s.connected != connected
+	s.connected = connected
+	return changed
+}
@@ -0,0 +1,88 @@
+package osc_test
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"bekuba.de/go-osc"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubscribe(t *testing.T) {
+	// mixer replies to /xremote with /status, if it is online
+	mixer, err := osc.NewNode("127.0.0.1:0")
+	assert.NoError(t, err)
+	defer mixer.Close()
+	addr := mixer.Conn().LocalAddr().String()
+
+	var online atomic.Bool
+	var renewals atomic.Int32
+	online.Store(true)
+
+	d := osc.NewStandardDispatcher()
+	err = d.AddMsgHandlerExt("/xremote", func(msg *osc.Message, raddr net.Addr) {
+		renewals.Add(1)
+		if online.Load() {
+			err := mixer.SendMsgTo(raddr.String(), "/status", "active")
+			assert.NoError(t, err)
+		}
+	})
+	assert.NoError(t, err)
+	go mixer.ListenAndServe(d)
+
+	app, err := osc.NewNode("127.0.0.1:0")
+	assert.NoError(t, err)
+	defer app.Close()
+	go app.ListenAndServe(nil)
+
+	events := make(chan bool, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sub, err := app.Subscribe(ctx, addr, osc.NewMessage("/xremote"), osc.SubscriptionOptions{
+		Interval:       20 * time.Millisecond,
+		Timeout:        100 * time.Millisecond,
+		OnConnected:    func(addr net.Addr) { events <- true },
+		OnDisconnected: func(addr net.Addr) { events <- false },
+	})
+	assert.NoError(t, err)
+
+	expect := func(connected bool) {
+		select {
+		case e := <-events:
+			assert.Equal(t, connected, e)
+			assert.Equal(t, connected, sub.Connected())
+		case <-time.After(time.Second):
+			t.Fatalf("no event, expected connected = %v", connected)
+		}
+	}
+
+	expect(true)
+	assert.WithinDuration(t, time.Now(), sub.LastSeen(), 100*time.Millisecond)
+
+	// peer is silent
+	online.Store(false)
+	expect(false)
+
+	// peer is back
+	online.Store(true)
+	expect(true)
+
+	assert.Greater(t, renewals.Load(), int32(5))
+
+	// stop the subscription
+	cancel()
+	select {
+	case <-sub.Done():
+	case <-time.After(time.Second):
+		t.Fatal("subscription not stopped")
+	}
+
+	n := renewals.Load()
+	time.Sleep(60 * time.Millisecond)
+	assert.Equal(t, n, renewals.Load())
+}
@@ -0,0 +1,496 @@
+package osc
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// textToken is a token of the text syntax.
+type textToken struct {
+	kind textTokenKind
+	// the word, the unquoted string or the blob without brackets
+	text string
+}
+
+type textTokenKind int
+
+const (
+	textWord textTokenKind = iota
+	textString
+	textBlob
+	textOpen
+	textClose
+	// ';' or a line break
+	textSeparator
+)
+
+// ParsePacket parses the text syntax of a message (see ParseMessage) or a
+// bundle (see ParseBundle).
+func ParsePacket(s string) (Packet, error) {
+	p, err := newTextParser(s)
+	if err != nil {
+		return nil, err
+	}
+	packet, err := p.packet()
+	if err != nil {
+		return nil, err
+	}
+	return packet, p.end()
+}
+
+// ParseMessage parses the text syntax of a message, the format of
+// Message.String:
+//
+//	/address ,ifsbhtdTFN 1 2.5 "text" [1 2 3] 4 5 0.25 true false Nil
+//
+// The arguments are parsed by the type tags. The values of T, F and N are
+// optional. Blobs are lists of decimal bytes, "hex:" or "base64:" followed by
+// the encoded bytes, or hex digits. Timetags are numbers, "immediate", "now" or
+// RFC 3339 times. Strings are quoted with Go escapes or, without spaces and
+// special characters, unquoted.
+//
+// Without type tag string, the types are inferred: quoted strings, blobs,
+// true, false, Nil, int32 (int64 if out of range), float32 and unquoted
+// strings. Untyped messages are written as "/address untyped [1 2 3]".
+func ParseMessage(s string) (*Message, error) {
+	p, err := newTextParser(s)
+	if err != nil {
+		return nil, err
+	}
+	msg, err := p.message()
+	if err != nil {
+		return nil, err
+	}
+	return msg, p.end()
+}
+
+// ParseBundle parses the text syntax of a bundle, the format of
+// Bundle.String:
+//
+//	#bundle <timetag> { /address ,i 1; #bundle immediate { /address ,f 0.5 } }
+//
+// The elements are separated by semicolons or line breaks. The timetag has
+// the syntax of a timetag argument (see ParseMessage).
+func ParseBundle(s string) (*Bundle, error) {
+	p, err := newTextParser(s)
+	if err != nil {
+		return nil, err
+	}
+	bundle, err := p.bundle()
+	if err != nil {
+		return nil, err
+	}
+	return bundle, p.end()
+}
+
+// textParser parses the tokens of the text syntax.
+type textParser struct {
+	tokens []textToken
+	pos    int
+}
+
+// newTextParser splits s into tokens.
+func newTextParser(s string) (*textParser, error) {
+	var tokens []textToken
+
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '\n' || c == ';':
+			tokens = append(tokens, textToken{kind: textSeparator})
+			i++
+
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+
+		case c == '{':
+			tokens = append(tokens, textToken{kind: textOpen})
+			i++
+
+		case c == '}':
+			tokens = append(tokens, textToken{kind: textClose})
+			i++
+
+		case c == '"':
+			end := i + 1
+			for end < len(s) && s[end] != '"' {
+				if s[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(s) {
+				return nil, fmt.Errorf("%w: unterminated string", ErrorInvalidSyntax)
+			}
+			str, err := strconv.Unquote(s[i : end+1])
+			if err != nil {
+				return nil, fmt.Errorf("%w: string %s: %v", ErrorInvalidSyntax, s[i:end+1], err)
+			}
+			tokens = append(tokens, textToken{kind: textString, text: str})
+			i = end + 1
+
+		case c == '[':
+			end := strings.IndexByte(s[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("%w: unterminated blob", ErrorInvalidSyntax)
+			}
+			tokens = append(tokens, textToken{kind: textBlob, text: s[i+1 : i+end]})
+			i += end + 1
+
+		default:
+			// braces and brackets of address patterns belong to the address
+			address := s[i] == '/'
+			depth, bracket := 0, false
+			end := i
+			for ; end < len(s); end++ {
+				c := s[end]
+				if c == ' ' || c == '\t' || c == '\r' || c == '\n' || c == ';' || c == '"' {
+					break
+				}
+				if c == '[' && address && !bracket {
+					bracket = true
+				} else if c == ']' && bracket {
+					bracket = false
+				} else if c == '{' && address {
+					depth++
+				} else if c == '}' && depth > 0 {
+					depth--
+				} else if c == '{' || c == '}' || c == '[' {
+					break
+				}
+			}
+			tokens = append(tokens, textToken{kind: textWord, text: s[i:end]})
+			i = end
+		}
+	}
+
+	return &textParser{tokens: tokens}, nil
+}
+
+// peek returns the next token or false at the end.
+func (p *textParser) peek() (textToken, bool) {
+	if p.pos >= len(p.tokens) {
+		return textToken{}, false
+	}
+	return p.tokens[p.pos], true
+}
+
+// skipSeparators skips all separators.
+func (p *textParser) skipSeparators() {
+	for t, ok := p.peek(); ok && t.kind == textSeparator; t, ok = p.peek() {
+		p.pos++
+	}
+}
+
+// end returns an error if there are tokens left.
+func (p *textParser) end() error {
+	p.skipSeparators()
+	if p.pos < len(p.tokens) {
+		return fmt.Errorf("%w: unexpected %s", ErrorInvalidSyntax, p.tokens[p.pos])
+	}
+	return nil
+}
+
+// String returns the token for error messages.
+func (t textToken) String() string {
+	switch t.kind {
+	case textString:
+		return strconv.Quote(t.text)
+	case textBlob:
+		return "[" + t.text + "]"
+	case textOpen:
+		return "'{'"
+	case textClose:
+		return "'}'"
+	case textSeparator:
+		return "separator"
+	}
+	return strconv.Quote(t.text)
+}
+
+// packet parses a message or a bundle.
+func (p *textParser) packet() (Packet, error) {
+	p.skipSeparators()
+	if t, ok := p.peek(); ok && t.kind == textWord && t.text == "#bundle" {
+		return p.bundle()
+	}
+	return p.message()
+}
+
+// bundle parses a bundle.
+func (p *textParser) bundle() (*Bundle, error) {
+	p.skipSeparators()
+	if t, ok := p.peek(); !ok || t.kind != textWord || t.text != "#bundle" {
+		return nil, fmt.Errorf("%w: missing #bundle", ErrorInvalidSyntax)
+	}
+	p.pos++
+
+	t, ok := p.peek()
+	if !ok || t.kind != textWord {
+		return nil, fmt.Errorf("%w: missing bundle timetag", ErrorInvalidSyntax)
+	}
+	timetag, err := parseTimetagText(t.text)
+	if err != nil {
+		return nil, err
+	}
+	p.pos++
+
+	if t, ok := p.peek(); !ok || t.kind != textOpen {
+		return nil, fmt.Errorf("%w: missing '{' of bundle", ErrorInvalidSyntax)
+	}
+	p.pos++
+
+	bundle := &Bundle{Timetag: timetag, Messages: []*Message{}, Bundles: []*Bundle{}}
+	for {
+		p.skipSeparators()
+		t, ok := p.peek()
+		if !ok {
+			return nil, fmt.Errorf("%w: missing '}' of bundle", ErrorInvalidSyntax)
+		}
+		if t.kind == textClose {
+			p.pos++
+			return bundle, nil
+		}
+
+		packet, err := p.packet()
+		if err != nil {
+			return nil, err
+		}
+		if err := bundle.Append(packet); err != nil {
+			return nil, err
+		}
+
+		// elements end with a separator or the end of the bundle
+		if t, ok := p.peek(); ok && t.kind != textSeparator && t.kind != textClose {
+			return nil, fmt.Errorf("%w: unexpected %s", ErrorInvalidSyntax, t)
+		}
+	}
+}
+
+// message parses a message.
+func (p *textParser) message() (*Message, error) {
+	p.skipSeparators()
+	t, ok := p.peek()
+	if !ok || t.kind != textWord || !strings.HasPrefix(t.text, "/") {
+		return nil, fmt.Errorf("%w: missing OSC address", ErrorInvalidSyntax)
+	}
+	p.pos++
+	msg := &Message{Address: t.text}
+
+	// the arguments up to the next separator or the end of a bundle
+	var args []textToken
+	for t, ok := p.peek(); ok && t.kind != textSeparator && t.kind != textClose; t, ok = p.peek() {
+		if t.kind == textOpen {
+			return nil, fmt.Errorf("%w: unexpected '{'", ErrorInvalidSyntax)
+		}
+		args = append(args, t)
+		p.pos++
+	}
+
+	if len(args) > 0 && args[0].kind == textWord && args[0].text == "untyped" {
+		if len(args) != 2 || args[1].kind != textBlob {
+			return nil, fmt.Errorf("%w: untyped message without blob", ErrorInvalidSyntax)
+		}
+		raw, err := parseBlobText(args[1])
+		if err != nil {
+			return nil, err
+		}
+		msg.Untyped, msg.RawArguments = true, raw
+		return msg, nil
+	}
+
+	if len(args) > 0 && args[0].kind == textWord && strings.HasPrefix(args[0].text, ",") {
+		return msg, parseTypedArguments(msg, args[0].text[1:], args[1:])
+	}
+
+	for _, arg := range args {
+		v, err := inferArgumentText(arg)
+		if err != nil {
+			return nil, err
+		}
+		msg.Arguments = append(msg.Arguments, v)
+	}
+	return msg, nil
+}
+
+// parseTypedArguments appends the arguments args with the type tags to msg.
+func parseTypedArguments(msg *Message, tags string, args []textToken) error {
+	for _, tag := range tags {
+		// arguments with optional value
+		var value any
+		switch tag {
+		case 'T':
+			value = true
+		case 'F':
+			value = false
+		case 'N':
+			value = nil
+		}
+		if tag == 'T' || tag == 'F' || tag == 'N' {
+			if len(args) > 0 && args[0].kind == textWord {
+				switch args[0].text {
+				case "true", "false", "Nil", "nil":
+					if v, _ := inferArgumentText(args[0]); v != value {
+						return fmt.Errorf("%w: %s of type %c", ErrorInvalidSyntax, args[0], tag)
+					}
+					args = args[1:]
+				}
+			}
+			msg.Arguments = append(msg.Arguments, value)
+			continue
+		}
+
+		if len(args) == 0 {
+			return fmt.Errorf("%w: missing argument of type %c", ErrorInvalidSyntax, tag)
+		}
+		arg := args[0]
+		args = args[1:]
+
+		v, err := parseArgumentText(tag, arg)
+		if err != nil {
+			return err
+		}
+		msg.Arguments = append(msg.Arguments, v)
+	}
+
+	if len(args) > 0 {
+		return fmt.Errorf("%w: %d arguments without type tag", ErrorInvalidSyntax, len(args))
+	}
+	return nil
+}
+
+// parseArgumentText returns the argument arg of the type tag.
+func parseArgumentText(tag rune, arg textToken) (any, error) {
+	if tag == 's' {
+		if arg.kind != textString && arg.kind != textWord {
+			return nil, fmt.Errorf("%w: %s of type s", ErrorInvalidSyntax, arg)
+		}
+		return arg.text, nil
+	}
+	if tag == 'b' {
+		return parseBlobText(arg)
+	}
+	if arg.kind != textWord {
+		return nil, fmt.Errorf("%w: %s of type %c", ErrorInvalidSyntax, arg, tag)
+	}
+
+	var v any
+	var err error
+	switch tag {
+	case 'i':
+		var i int64
+		i, err = strconv.ParseInt(arg.text, 0, 32)
+		v = int32(i)
+	case 'h':
+		v, err = strconv.ParseInt(arg.text, 0, 64)
+	case 'f':
+		var f float64
+		f, err = strconv.ParseFloat(arg.text, 32)
+		v = float32(f)
+	case 'd':
+		v, err = strconv.ParseFloat(arg.text, 64)
+	case 't':
+		return parseTimetagText(arg.text)
+	default:
+		return nil, fmt.Errorf("%w: unsupported type tag %c", ErrorInvalidSyntax, tag)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s of type %c", ErrorInvalidSyntax, arg, tag)
+	}
+	return v, nil
+}
+
+// inferArgumentText returns the argument arg of the inferred type.
+func inferArgumentText(arg textToken) (any, error) {
+	switch arg.kind {
+	case textString:
+		return arg.text, nil
+	case textBlob:
+		return parseBlobText(arg)
+	}
+
+	s := arg.text
+	switch {
+	case s == "true":
+		return true, nil
+	case s == "false":
+		return false, nil
+	case s == "Nil" || s == "nil":
+		return nil, nil
+	case strings.HasPrefix(s, "hex:") || strings.HasPrefix(s, "base64:"):
+		return parseBlobText(arg)
+	}
+
+	if i, err := strconv.ParseInt(s, 10, 32); err == nil {
+		return int32(i), nil
+	}
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return i, nil
+	}
+	// words like "info" aren't floats
+	if strings.IndexFunc(s, unicode.IsDigit) >= 0 || s == "NaN" || s == "+Inf" || s == "-Inf" {
+		if f, err := strconv.ParseFloat(s, 32); err == nil {
+			return float32(f), nil
+		}
+	}
+	return s, nil
+}
+
+// parseBlobText returns the bytes of a blob: a list of decimal bytes,
+// "hex:" or "base64:" followed by the encoded bytes, or hex digits.
+func parseBlobText(arg textToken) ([]byte, error) {
+	switch arg.kind {
+	case textBlob:
+		fields := strings.Fields(arg.text)
+		data := make([]byte, len(fields))
+		for i, field := range fields {
+			b, err := strconv.ParseUint(field, 10, 8)
+			if err != nil {
+				return nil, fmt.Errorf("%w: blob byte %q", ErrorInvalidSyntax, field)
+			}
+			data[i] = byte(b)
+		}
+		return data, nil
+
+	case textWord:
+		var data []byte
+		var err error
+		switch s := arg.text; {
+		case strings.HasPrefix(s, "base64:"):
+			data, err = base64.StdEncoding.DecodeString(s[len("base64:"):])
+		case strings.HasPrefix(s, "hex:"):
+			data, err = hex.DecodeString(s[len("hex:"):])
+		default:
+			data, err = hex.DecodeString(strings.TrimPrefix(s, "0x"))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: blob %s", ErrorInvalidSyntax, arg)
+		}
+		return data, nil
+	}
+	return nil, fmt.Errorf("%w: %s of type b", ErrorInvalidSyntax, arg)
+}
+
+// parseTimetagText returns the timetag of a number, "immediate", "now" or an
+// RFC 3339 time.
+func parseTimetagText(s string) (Timetag, error) {
+	switch s {
+	case "immediate":
+		return NewImmediateTimetag(), nil
+	case "now":
+		return NewTimetag(), nil
+	}
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return NewTimetagFromTime(t), nil
+	}
+	v, err := strconv.ParseUint(s, 0, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: timetag %q", ErrorInvalidSyntax, s)
+	}
+	return Timetag(v), nil
+}
@@ -0,0 +1,127 @@
+package osc_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"bekuba.de/go-osc"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMessageRoundTrip(t *testing.T) {
+	for _, msg := range []*osc.Message{
+		osc.NewMessage("/empty"),
+		osc.NewMessage("/int", int32(-42), int32(math.MaxInt32), int64(math.MinInt64)),
+		osc.NewMessage("/float", float32(0.5), float32(1e20), float64(-0.1), float32(math.Inf(1))),
+		osc.NewMessage("/string", "hello world", "", "quote \" and \\ and\nline", "{}; []", "untyped"),
+		osc.NewMessage("/blob", []byte{0, 1, 255}, []byte{}),
+		osc.NewMessage("/values", true, false, nil, osc.Timetag(1<<32+1)),
+		osc.NewMessage("/ch/{1,2}/mix/fader", float32(0.75)),
+		osc.NewMessage("/ch/[1-3]/fader", float32(0.5)),
+		osc.NewMessage("/ch/[!1]/{mix,eq}/[a-c]", "[1 2]"),
+		{Address: "/untyped", Untyped: true, RawArguments: []byte{0, 0, 0, 1}},
+	} {
+		parsed, err := osc.ParseMessage(msg.String())
+		if assert.NoError(t, err, msg.String()) {
+			assert.Equal(t, msg, parsed, msg.String())
+			assert.Equal(t, msg.String(), parsed.String())
+		}
+	}
+
+	// NaN isn't equal to itself
+	parsed, err := osc.ParseMessage(osc.NewMessage("/nan", float32(math.NaN())).String())
+	assert.NoError(t, err)
+	assert.True(t, math.IsNaN(float64(parsed.Arguments[0].(float32))))
+}
+
+func TestParseMessage(t *testing.T) {
+	for _, tt := range []struct {
+		s    string
+		want *osc.Message
+	}{
+		{"/a ,ihs 0x10 -1 text", osc.NewMessage("/a", int32(16), int64(-1), "text")},
+		{"/a ,TFN", osc.NewMessage("/a", true, false, nil)},
+		{"/a ,iTi 1 2", osc.NewMessage("/a", int32(1), true, int32(2))},
+		{"/a ,bbbb hex:c0ffee base64:AQI= 0xff [3 4]", osc.NewMessage("/a", []byte{0xc0, 0xff, 0xee}, []byte{1, 2}, []byte{0xff}, []byte{3, 4})},
+		{"/a ,tt immediate 1970-01-01T00:00:01Z", osc.NewMessage("/a", osc.NewImmediateTimetag(), osc.NewTimetagFromTime(time.Unix(1, 0)))},
+		{`/a 1 9999999999 0.5 1e3 true false nil "1" info [1] hex:ff`, osc.NewMessage("/a", int32(1), int64(9999999999), float32(0.5), float32(1000), true, false, nil, "1", "info", []byte{1}, []byte{0xff})},
+		{"  /a ,s \"\\u00e4\"  \n", osc.NewMessage("/a", "ä")},
+	} {
+		msg, err := osc.ParseMessage(tt.s)
+		assert.NoError(t, err, tt.s)
+		assert.Equal(t, tt.want, msg, tt.s)
+	}
+}
+
+func TestParseMessageError(t *testing.T) {
+	for _, s := range []string{
+		"",
+		"address ,i 1",
+		"/a ,i one",
+		"/a ,i 9999999999",
+		"/a ,ii 1",
+		"/a ,i 1 2",
+		"/a ,T false",
+		"/a ,c x",
+		"/a ,b xyz",
+		"/a ,b [256]",
+		"/a ,t tomorrow",
+		`/a "unterminated`,
+		`/a "\q"`,
+		"/a [1 2",
+		"/a untyped",
+		"/a 1; /b 2",
+		"/a { 1 }",
+		"#bundle 1 { }",
+	} {
+		_, err := osc.ParseMessage(s)
+		assert.ErrorIs(t, err, osc.ErrorInvalidSyntax, s)
+	}
+}
+
+func TestParseBundle(t *testing.T) {
+	inner := osc.NewBundle(time.Unix(1, 0))
+	inner.Messages = append(inner.Messages, osc.NewMessage("/inner", "a;b}"))
+	bundle := osc.NewBundle(time.Unix(2, 0))
+	bundle.Messages = append(bundle.Messages, osc.NewMessage("/a", int32(1)), osc.NewMessage("/b"))
+	bundle.Bundles = append(bundle.Bundles, inner, osc.NewBundle(time.Unix(3, 0)))
+
+	parsed, err := osc.ParseBundle(bundle.String())
+	assert.NoError(t, err, bundle.String())
+	assert.Equal(t, bundle, parsed)
+	assert.Equal(t, bundle.String(), parsed.String())
+
+	packet, err := osc.ParsePacket(bundle.String())
+	assert.NoError(t, err)
+	assert.Equal(t, bundle, packet)
+
+	packet, err = osc.ParsePacket("/a ,i 1")
+	assert.NoError(t, err)
+	assert.Equal(t, osc.NewMessage("/a", int32(1)), packet)
+
+	// line breaks separate the elements
+	parsed, err = osc.ParseBundle("#bundle immediate {\n\t/a ,f 0.5\n\n\t#bundle 1 {\n\t\t/b\n\t}\n}\n")
+	assert.NoError(t, err)
+	assert.Equal(t, osc.NewImmediateTimetag(), parsed.Timetag)
+	assert.Equal(t, []*osc.Message{osc.NewMessage("/a", float32(0.5))}, parsed.Messages)
+	if assert.Len(t, parsed.Bundles, 1) {
+		assert.Equal(t, osc.Timetag(1), parsed.Bundles[0].Timetag)
+		assert.Equal(t, []*osc.Message{osc.NewMessage("/b")}, parsed.Bundles[0].Messages)
+	}
+
+	for _, s := range []string{
+		"/a",
+		"#bundle",
+		"#bundle 1",
+		"#bundle 1 {",
+		"#bundle 1 { /a } }",
+		"#bundle 1 { /a #bundle 2 { } }",
+		"#bundle 1 { a }",
+		"#bundle soon { }",
+	} {
+		_, err := osc.ParseBundle(s)
+		assert.ErrorIs(t, err, osc.ErrorInvalidSyntax, s)
+	}
+}
@@ -0,0 +1,86 @@
+package osc
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"time"
+)
+
+// tlsHandshakeTimeout is the time a TLS peer has to complete the handshake.
+const tlsHandshakeTimeout = 10 * time.Second
+
+// TLSAddr is the address of a TLS peer with its certificates. Handlers of a
+// StreamServer serving TLS receive it as net.Addr.
+type TLSAddr struct {
+	net.Addr
+	// PeerCertificates are the certificates of the peer, the leaf
+	// certificate first. They may be unverified, e.g. with ClientAuth
+	// tls.RequestClientCert.
+	PeerCertificates []*x509.Certificate
+	// VerifiedChains are the verified certificate chains of the peer, the
+	// leaf certificate first, e.g. with ClientAuth
+	// tls.RequireAndVerifyClientCert.
+	VerifiedChains [][]*x509.Certificate
+}
+
+// Network returns "tls".
+func (a *TLSAddr) Network() string {
+	return "tls"
+}
+
+// CommonName returns the common name of the verified leaf certificate of the
+// peer or an empty string, if the peer sent no certificate or it wasn't
+// verified.
+func (a *TLSAddr) CommonName() string {
+	if len(a.VerifiedChains) == 0 || len(a.VerifiedChains[0]) == 0 {
+		return ""
+	}
+	return a.VerifiedChains[0][0].Subject.CommonName
+}
+
+// newTLSAddr returns the address of the peer of the TLS connection state.
+func newTLSAddr(addr net.Addr, state tls.ConnectionState) *TLSAddr {
+	return &TLSAddr{Addr: addr, PeerCertificates: state.PeerCertificates, VerifiedChains: state.VerifiedChains}
+}
+
+// DialTLS opens a TLS connection to raddr. For mutual authentication config
+// contains the client certificate.
+func DialTLS(raddr string, framing Framing, config *tls.Config) (*StreamConn, error) {
+	dialer := &net.Dialer{Timeout: tlsHandshakeTimeout}
+	conn, err := tls.DialWithDialer(dialer, "tcp", raddr, config)
+	if err != nil {
+		return nil, err
+	}
+
+	c := NewStreamConn(conn, framing)
+	c.addr = newTLSAddr(conn.RemoteAddr(), conn.ConnectionState())
+	return c, nil
+}
+
+// ListenAndServeTLS listens on the TCP address laddr and serves all
+// connections with TLS. For mutual authentication set config.ClientAuth to
+// tls.RequireAndVerifyClientCert and config.ClientCAs.
+func (s *StreamServer) ListenAndServeTLS(laddr string, config *tls.Config) error {
+	l, err := tls.Listen("tcp", laddr, config)
+	if err != nil {
+		return ErrorOscAddress
+	}
+	return s.Serve(l)
+}
+
+// tlsHandshake runs the handshake of a TLS server connection and returns the
+// address of the peer.
+func tlsHandshake(conn *tls.Conn) (*TLSAddr, error) {
+	if err := conn.SetDeadline(time.Now().Add(tlsHandshakeTimeout)); err != nil {
+		return nil, err
+	}
+	if err := conn.Handshake(); err != nil {
+		return nil, err
+	}
+	if err := conn.SetDeadline(time.Time{}); err != nil {
+		return nil, err
+	}
+
+	return newTLSAddr(conn.RemoteAddr(), conn.ConnectionState()), nil
+}
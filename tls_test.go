@@ -0,0 +1,193 @@
+package osc_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"bekuba.de/go-osc"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testCA is a certificate authority for locally generated test certificates.
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pool *x509.CertPool
+}
+
+func newTestCA(t *testing.T) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "go-osc test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	assert.NoError(t, err)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return &testCA{cert: cert, key: key, pool: pool}
+}
+
+// issue returns a certificate for commonName signed by the CA.
+func (ca *testCA) issue(t *testing.T, commonName string, usage x509.ExtKeyUsage) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	assert.NoError(t, err)
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func TestTLS(t *testing.T) {
+	ca := newTestCA(t)
+	serverConfig := &tls.Config{
+		Certificates: []tls.Certificate{ca.issue(t, "rig", x509.ExtKeyUsageServerAuth)},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    ca.pool,
+	}
+
+	peers := make(chan string, 10)
+	d := osc.NewStandardDispatcher()
+	server := osc.NewStreamServer(d, osc.FramingSLIP)
+	err := d.AddMsgHandlerExt("/cue/go", func(msg *osc.Message, addr net.Addr) {
+		tlsAddr, ok := addr.(*osc.TLSAddr)
+		assert.True(t, ok)
+		peers <- tlsAddr.CommonName()
+		err := server.SendTo(addr, osc.NewMessage("/cue/done"))
+		assert.NoError(t, err)
+	})
+	assert.NoError(t, err)
+
+	l, err := tls.Listen("tcp", "127.0.0.1:0", serverConfig)
+	assert.NoError(t, err)
+	go server.Serve(l)
+	defer server.Close()
+	addr := l.Addr().String()
+
+	client, err := osc.DialTLS(addr, osc.FramingSLIP, &tls.Config{
+		Certificates: []tls.Certificate{ca.issue(t, "console-1", x509.ExtKeyUsageClientAuth)},
+		RootCAs:      ca.pool,
+	})
+	assert.NoError(t, err)
+	defer client.Close()
+	assert.Equal(t, "rig", client.RemoteAddr().(*osc.TLSAddr).CommonName())
+	assert.Equal(t, "tls", client.RemoteAddr().Network())
+
+	err = client.SendMsg("/cue/go")
+	assert.NoError(t, err)
+
+	select {
+	case peer := <-peers:
+		assert.Equal(t, "console-1", peer)
+	case <-time.After(time.Second):
+		t.Fatal("timeout")
+	}
+
+	p, err := client.Read()
+	assert.NoError(t, err)
+	assert.Equal(t, osc.NewMessage("/cue/done"), p)
+
+	// without client certificate
+	anonymous, err := osc.DialTLS(addr, osc.FramingSLIP, &tls.Config{RootCAs: ca.pool})
+	if err == nil {
+		// TLS 1.3 reports the rejected certificate on the first read
+		_ = anonymous.SendMsg("/cue/go")
+		_, err = anonymous.Read()
+		anonymous.Close()
+	}
+	assert.Error(t, err)
+
+	// with a certificate of another CA
+	other := newTestCA(t)
+	intruder, err := osc.DialTLS(addr, osc.FramingSLIP, &tls.Config{
+		Certificates: []tls.Certificate{other.issue(t, "intruder", x509.ExtKeyUsageClientAuth)},
+		RootCAs:      ca.pool,
+	})
+	if err == nil {
+		_ = intruder.SendMsg("/cue/go")
+		_, err = intruder.Read()
+		intruder.Close()
+	}
+	assert.Error(t, err)
+
+	// the server certificate isn't trusted
+	_, err = osc.DialTLS(addr, osc.FramingSLIP, &tls.Config{RootCAs: other.pool})
+	assert.Error(t, err)
+
+	select {
+	case peer := <-peers:
+		t.Errorf("unexpected peer %q", peer)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestTLSUnverifiedClient(t *testing.T) {
+	ca := newTestCA(t)
+	serverConfig := &tls.Config{
+		Certificates: []tls.Certificate{ca.issue(t, "rig", x509.ExtKeyUsageServerAuth)},
+		ClientAuth:   tls.RequestClientCert,
+	}
+
+	peers := make(chan *osc.TLSAddr, 10)
+	d := osc.NewStandardDispatcher()
+	server := osc.NewStreamServer(d, osc.FramingSLIP)
+	err := d.AddMsgHandlerExt("/cue/go", func(msg *osc.Message, addr net.Addr) {
+		peers <- addr.(*osc.TLSAddr)
+	})
+	assert.NoError(t, err)
+
+	l, err := tls.Listen("tcp", "127.0.0.1:0", serverConfig)
+	assert.NoError(t, err)
+	go server.Serve(l)
+	defer server.Close()
+
+	// the certificate is requested, but not verified
+	other := newTestCA(t)
+	client, err := osc.DialTLS(l.Addr().String(), osc.FramingSLIP, &tls.Config{
+		Certificates: []tls.Certificate{other.issue(t, "intruder", x509.ExtKeyUsageClientAuth)},
+		RootCAs:      ca.pool,
+	})
+	assert.NoError(t, err)
+	defer client.Close()
+	assert.NoError(t, client.SendMsg("/cue/go"))
+
+	select {
+	case peer := <-peers:
+		assert.Len(t, peer.PeerCertificates, 1)
+		assert.Empty(t, peer.VerifiedChains)
+		assert.Equal(t, "", peer.CommonName())
+	case <-time.After(time.Second):
+		t.Fatal("timeout")
+	}
+}
@@ -0,0 +1,98 @@
+//go:build unix
+
+package osc_test
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"bekuba.de/go-osc"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnixgram(t *testing.T) {
+	dir := t.TempDir()
+	path1 := filepath.Join(dir, "app1.sock")
+	path2 := filepath.Join(dir, "app2.sock")
+
+	app1, err := osc.NewNodeNetwork(osc.NetworkUnixgram, path1)
+	assert.NoError(t, err)
+	assert.Nil(t, app1.Conn())
+	assert.NotNil(t, app1.PacketConn())
+
+	app2, err := osc.NewNodeNetwork(osc.NetworkUnixgram, path2)
+	assert.NoError(t, err)
+	defer app2.Close()
+
+	addrs := make(chan net.Addr, 10)
+	d := osc.NewStandardDispatcher()
+	err = d.AddMsgHandlerExt(ping, func(msg *osc.Message, addr net.Addr) {
+		addrs <- addr
+		err := app1.SendMsgTo(addr.String(), pong, msg.Arguments...)
+		assert.NoError(t, err)
+	})
+	assert.NoError(t, err)
+	go app1.ListenAndServe(d)
+
+	err = app2.SendMsgTo(path1, ping, int32(1))
+	assert.NoError(t, err)
+
+	select {
+	case addr := <-addrs:
+		assert.Equal(t, &net.UnixAddr{Name: path2, Net: osc.NetworkUnixgram}, addr)
+	case <-time.After(time.Second):
+		t.Fatal("timeout")
+	}
+
+	app2.ReadTimeout = time.Second
+	p, addr, err := app2.Read()
+	assert.NoError(t, err)
+	assert.Equal(t, osc.NewMessage(pong, int32(1)), p)
+	assert.Equal(t, path1, addr.String())
+
+	// UDP only
+	assert.ErrorIs(t, app1.SetBroadcast(true), osc.ErrorNotSupported)
+
+	// the socket file is removed
+	_, err = os.Stat(path1)
+	assert.NoError(t, err)
+	app1.Close()
+	_, err = os.Stat(path1)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	// the path can be used again
+	app1, err = osc.NewNodeNetwork(osc.NetworkUnixgram, path1)
+	assert.NoError(t, err)
+	app1.Close()
+}
+
+func TestUnixgramAbstract(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("abstract namespace is Linux only")
+	}
+
+	name1 := "@go-osc-test-" + t.Name() + "-1"
+	name2 := "@go-osc-test-" + t.Name() + "-2"
+
+	app1, err := osc.NewNodeNetwork(osc.NetworkUnixgram, name1)
+	assert.NoError(t, err)
+	defer app1.Close()
+
+	app2, err := osc.NewNodeNetwork(osc.NetworkUnixgram, name2)
+	assert.NoError(t, err)
+	defer app2.Close()
+
+	err = app2.SendTo(name1, osc.NewMessage(ping))
+	assert.NoError(t, err)
+
+	app1.ReadTimeout = time.Second
+	p, addr, err := app1.Read()
+	assert.NoError(t, err)
+	assert.Equal(t, osc.NewMessage(ping), p)
+	assert.Equal(t, name2, addr.String())
+}
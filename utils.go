@@ -1,6 +1,8 @@
 package osc
 
 import (
+	"fmt"
+	"net"
 	"regexp"
 	"strings"
 )
@@ -15,6 +17,58 @@ func addressExists(addr string, handlers map[string]Handler) bool {
 	return false
 }
 
+// sameAddr returns true if a and b are the same network address.
+func sameAddr(a, b net.Addr) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	ua, ok1 := a.(*net.UDPAddr)
+	ub, ok2 := b.(*net.UDPAddr)
+	if ok1 && ok2 {
+		return ua.Port == ub.Port && ua.IP.Equal(ub.IP) && ua.Zone == ub.Zone
+	}
+	return a.Network() == b.Network() && a.String() == b.String()
+}
+
+// addrIP returns the IP address of addr or nil, e.g. for Unix domain sockets.
+func addrIP(addr net.Addr) net.IP {
+	switch a := addr.(type) {
+	case *net.UDPAddr:
+		return a.IP
+	case *net.TCPAddr:
+		return a.IP
+	case *net.IPAddr:
+		return a.IP
+	case *TLSAddr:
+		return addrIP(a.Addr)
+	case *WebSocketAddr:
+		return addrIP(a.Addr)
+	}
+	return nil
+}
+
+// parseNetwork parses a network in CIDR notation or an IP address.
+func parseNetwork(s string) (*net.IPNet, error) {
+	if _, network, err := net.ParseCIDR(s); err == nil {
+		return network, nil
+	}
+
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return nil, fmt.Errorf("%w: invalid network %q", ErrorOscAddressFormat, s)
+	}
+	bits := 8 * len(ip)
+	if ip4 := ip.To4(); ip4 != nil {
+		ip, bits = ip4, 32
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+}
+
+// hasPattern returns true if the OSC address contains pattern characters.
+func hasPattern(address string) bool {
+	return strings.ContainsAny(address, "*?[]{}")
+}
+
 // getRegEx compiles and returns a regular expression object for the given
 // address `pattern`.
 func getRegEx(pattern string) (*regexp.Regexp, error) {
@@ -37,6 +91,30 @@ func getRegEx(pattern string) (*regexp.Regexp, error) {
 	return regexp.Compile(pattern)
 }
 
+// messages returns all OSC messages of an OSC packet, including the messages
+// of nested bundles.
+func messages(packet Packet) []*Message {
+	switch p := packet.(type) {
+	case *Message:
+		return []*Message{p}
+	case *Bundle:
+		msgs := append([]*Message{}, p.Messages...)
+		for _, b := range p.Bundles {
+			msgs = append(msgs, messages(b)...)
+		}
+		return msgs
+	}
+	return nil
+}
+
+// boolToInt returns 1 for true and 0 for false.
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
 // getTypeTag returns the OSC type tag for the given argument.
 func getTypeTag(arg any) byte {
 	switch t := arg.(type) {
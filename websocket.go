@@ -0,0 +1,723 @@
+package osc
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultWebSocketWriteQueueSize is the number of packets queued per
+// connection of a WebSocketServer, if WebSocketServer.WriteQueueSize is not
+// set.
+const DefaultWebSocketWriteQueueSize = 256
+
+// websocketGUID is appended to Sec-WebSocket-Key to compute
+// Sec-WebSocket-Accept (RFC 6455).
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// websocketCloseTimeout is the time Close waits to send the close frame.
+const websocketCloseTimeout = time.Second
+
+// WebSocket opcodes
+const (
+	wsContinuation = 0x0
+	wsText         = 0x1
+	wsBinary       = 0x2
+	wsClose        = 0x8
+	wsPing         = 0x9
+	wsPong         = 0xa
+)
+
+// WebSocket close codes
+const (
+	wsCloseNormal          = 1000
+	wsCloseProtocolError   = 1002
+	wsCloseUnsupported     = 1003
+	wsCloseMessageTooLarge = 1009
+)
+
+// WebSocketAddr is the address of a WebSocket peer. Handlers receive it as
+// net.Addr of packets received by a WebSocketServer.
+type WebSocketAddr struct {
+	net.Addr
+}
+
+// Network returns "ws".
+func (a *WebSocketAddr) Network() string {
+	return "ws"
+}
+
+// WebSocketConn is a WebSocket connection, that carries one OSC packet per
+// binary frame (like osc.js).
+type WebSocketConn struct {
+	// MaxPacketSize is the maximum size of sent and received OSC packets in
+	// bytes. Zero means DefaultMaxPacketSize.
+	MaxPacketSize int
+	// DecodeOptions controls how received OSC packets are decoded.
+	DecodeOptions DecodeOptions
+
+	conn   net.Conn
+	reader *bufio.Reader
+	client bool
+	addr   *WebSocketAddr
+
+	writeMutex sync.Mutex
+	closed     atomic.Bool
+	// queue holds the packets to send of a server connection
+	queue chan []byte
+}
+
+// newWebSocketConn returns a WebSocket connection. Frames sent by a client are
+// masked.
+func newWebSocketConn(conn net.Conn, reader *bufio.Reader, client bool) *WebSocketConn {
+	return &WebSocketConn{
+		conn:   conn,
+		reader: reader,
+		client: client,
+		addr:   &WebSocketAddr{Addr: conn.RemoteAddr()},
+	}
+}
+
+// DialWebSocket opens a WebSocket connection to a "ws://" or "wss://" URL.
+func DialWebSocket(rawURL string) (*WebSocketConn, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, ErrorOscAddressFormat
+	}
+
+	var conn net.Conn
+	host := u.Host
+	switch u.Scheme {
+	case "ws":
+		if u.Port() == "" {
+			host = net.JoinHostPort(u.Hostname(), "80")
+		}
+		conn, err = net.Dial("tcp", host)
+	case "wss":
+		if u.Port() == "" {
+			host = net.JoinHostPort(u.Hostname(), "443")
+		}
+		conn, err = tls.Dial("tcp", host, &tls.Config{ServerName: u.Hostname()})
+	default:
+		return nil, ErrorOscAddressFormat
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := websocketHandshake(conn, u)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// websocketHandshake sends the opening handshake of a client.
+func websocketHandshake(conn net.Conn, u *url.URL) (*WebSocketConn, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	key := base64.StdEncoding.EncodeToString(nonce)
+
+	req := &http.Request{
+		Method:     http.MethodGet,
+		URL:        &url.URL{Path: u.Path, RawQuery: u.RawQuery},
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header: http.Header{
+			"Upgrade":               {"websocket"},
+			"Connection":            {"Upgrade"},
+			"Sec-WebSocket-Key":     {key},
+			"Sec-WebSocket-Version": {"13"},
+		},
+		Host: u.Host,
+	}
+	if req.URL.Path == "" {
+		req.URL.Path = "/"
+	}
+	if err := req.Write(conn); err != nil {
+		return nil, err
+	}
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		return nil, fmt.Errorf("%w: %s", ErrorWebSocketHandshake, resp.Status)
+	}
+	if !headerContains(resp.Header, "Upgrade", "websocket") ||
+		!headerContains(resp.Header, "Connection", "upgrade") ||
+		resp.Header.Get("Sec-WebSocket-Accept") != websocketAccept(key) {
+		return nil, ErrorWebSocketHandshake
+	}
+
+	return newWebSocketConn(conn, reader, true), nil
+}
+
+// websocketAccept returns the Sec-WebSocket-Accept value of key.
+func websocketAccept(key string) string {
+	h := sha1.Sum([]byte(key + websocketGUID))
+	return base64.StdEncoding.EncodeToString(h[:])
+}
+
+// headerContains returns true if the comma separated header values of name
+// contain token (case-insensitive).
+func headerContains(h http.Header, name, token string) bool {
+	for _, v := range h.Values(name) {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Send sends an OSC Bundle or an OSC Message in one binary frame.
+func (c *WebSocketConn) Send(packet Packet) error {
+	data, err := packet.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	return c.write(data)
+}
+
+// SendMsg sends an OSC Message (all int types converted to int32, see
+// SendMsgTo).
+func (c *WebSocketConn) SendMsg(path string, args ...any) error {
+	msg, err := newMessageFromArgs(path, args...)
+	if err != nil {
+		return err
+	}
+	return c.Send(msg)
+}
+
+// write writes a marshaled OSC packet in one binary frame.
+func (c *WebSocketConn) write(data []byte) error {
+	if c.closed.Load() {
+		return ErrorNodeClosed
+	}
+	if len(data) > c.maxPacketSize() {
+		return fmt.Errorf("%w: %d bytes (max. %d bytes)", ErrorPacketTooLarge, len(data), c.maxPacketSize())
+	}
+	if c.queue != nil {
+		select {
+		case c.queue <- data:
+			return nil
+		default:
+			// a stalled peer must not block the sender
+			c.abort()
+			return ErrorQueueFull
+		}
+	}
+	return c.writeFrame(wsBinary, data)
+}
+
+// writeQueue sends the queued packets until done is closed.
+func (c *WebSocketConn) writeQueue(done chan struct{}) {
+	for {
+		select {
+		case data := <-c.queue:
+			if err := c.writeFrame(wsBinary, data); err != nil {
+				c.abort()
+				return
+			}
+		case <-done:
+			return
+		}
+	}
+}
+
+// abort closes the connection without close frame.
+func (c *WebSocketConn) abort() {
+	c.closed.Store(true)
+	c.conn.Close()
+}
+
+// writeFrame writes one frame.
+func (c *WebSocketConn) writeFrame(opcode byte, payload []byte) error {
+	frame := make([]byte, 0, 14+len(payload))
+	frame = append(frame, 0x80|opcode)
+
+	var mask byte
+	if c.client {
+		mask = 0x80
+	}
+	switch n := len(payload); {
+	case n <= 125:
+		frame = append(frame, mask|byte(n))
+	case n <= 0xffff:
+		frame = append(frame, mask|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(n))
+	default:
+		frame = append(frame, mask|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(n))
+	}
+
+	if c.client {
+		var key [4]byte
+		if _, err := rand.Read(key[:]); err != nil {
+			return err
+		}
+		frame = append(frame, key[:]...)
+		start := len(frame)
+		frame = append(frame, payload...)
+		maskBytes(frame[start:], key)
+	} else {
+		frame = append(frame, payload...)
+	}
+
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+	_, err := c.conn.Write(frame)
+	return err
+}
+
+// maskBytes masks or unmasks data with key.
+func maskBytes(data []byte, key [4]byte) {
+	for i := range data {
+		data[i] ^= key[i%4]
+	}
+}
+
+// writeClose sends a close frame with code.
+func (c *WebSocketConn) writeClose(code uint16) error {
+	return c.writeFrame(wsClose, binary.BigEndian.AppendUint16(nil, code))
+}
+
+// fail closes the connection with code and returns err.
+func (c *WebSocketConn) fail(code uint16, err error) error {
+	_ = c.writeClose(code)
+	c.closed.Store(true)
+	c.conn.Close()
+	return err
+}
+
+// readFrame reads one frame with a payload of at most limit bytes.
+func (c *WebSocketConn) readFrame(limit int) (fin bool, opcode byte, payload []byte, err error) {
+	var header [2]byte
+	if _, err = io.ReadFull(c.reader, header[:]); err != nil {
+		return
+	}
+	fin = header[0]&0x80 != 0
+	opcode = header[0] & 0x0f
+	masked := header[1]&0x80 != 0
+
+	if header[0]&0x70 != 0 || masked == c.client {
+		err = c.fail(wsCloseProtocolError, ErrorWebSocketProtocol)
+		return
+	}
+
+	n := uint64(header[1] & 0x7f)
+	switch n {
+	case 126:
+		var b [2]byte
+		if _, err = io.ReadFull(c.reader, b[:]); err != nil {
+			return
+		}
+		n = uint64(binary.BigEndian.Uint16(b[:]))
+	case 127:
+		var b [8]byte
+		if _, err = io.ReadFull(c.reader, b[:]); err != nil {
+			return
+		}
+		n = binary.BigEndian.Uint64(b[:])
+	}
+
+	if opcode >= wsClose && (n > 125 || !fin) {
+		err = c.fail(wsCloseProtocolError, ErrorWebSocketProtocol)
+		return
+	}
+	if n > uint64(limit) {
+		err = c.fail(wsCloseMessageTooLarge, ErrorPacketTooLarge)
+		return
+	}
+
+	var key [4]byte
+	if masked {
+		if _, err = io.ReadFull(c.reader, key[:]); err != nil {
+			return
+		}
+	}
+	payload = make([]byte, n)
+	if _, err = io.ReadFull(c.reader, payload); err != nil {
+		return
+	}
+	if masked {
+		maskBytes(payload, key)
+	}
+	return
+}
+
+// Read retrieves the next OSC packet. Ping frames are answered. If the peer
+// closes the connection, io.EOF is returned.
+func (c *WebSocketConn) Read() (Packet, error) {
+	var data []byte
+	started := false
+
+	for {
+		limit := c.maxPacketSize() - len(data)
+		fin, opcode, payload, err := c.readFrame(max(limit, 125))
+		if err != nil {
+			if c.closed.Load() && !errors.Is(err, ErrorWebSocketProtocol) && !errors.Is(err, ErrorPacketTooLarge) {
+				return nil, io.EOF
+			}
+			return nil, err
+		}
+
+		switch opcode {
+		case wsPing:
+			if err := c.writeFrame(wsPong, payload); err != nil {
+				return nil, err
+			}
+			continue
+		case wsPong:
+			continue
+		case wsClose:
+			_ = c.fail(wsCloseNormal, nil)
+			return nil, io.EOF
+		case wsText:
+			return nil, c.fail(wsCloseUnsupported, fmt.Errorf("%w: text frame", ErrorWebSocketProtocol))
+		case wsBinary:
+			if started {
+				return nil, c.fail(wsCloseProtocolError, ErrorWebSocketProtocol)
+			}
+			started = true
+		case wsContinuation:
+			if !started {
+				return nil, c.fail(wsCloseProtocolError, ErrorWebSocketProtocol)
+			}
+		default:
+			return nil, c.fail(wsCloseProtocolError, ErrorWebSocketProtocol)
+		}
+
+		if len(data)+len(payload) > c.maxPacketSize() {
+			return nil, c.fail(wsCloseMessageTooLarge, ErrorPacketTooLarge)
+		}
+		data = append(data, payload...)
+		if fin {
+			break
+		}
+	}
+
+	return c.DecodeOptions.UnmarshalPacket(data)
+}
+
+// Serve retrieves OSC packets and dispatches them with the address of the
+// peer until the connection is closed. Packets, that can't be decoded, are
+// dropped.
+func (c *WebSocketConn) Serve(d Dispatcher) error {
+	return c.serve(d, nil)
+}
+
+// serve retrieves and dispatches OSC packets. w is notified of every packet.
+func (c *WebSocketConn) serve(d Dispatcher, w *watchers) error {
+	for {
+		packet, err := c.Read()
+		var decodeErr *DecodeError
+		if errors.As(err, &decodeErr) {
+			continue
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if w != nil {
+			w.notify(packet, c.addr)
+		}
+		if d != nil {
+			if err := d.Dispatch(packet, c.addr); err != nil {
+				c.Close()
+				return err
+			}
+		}
+	}
+}
+
+// RemoteAddr returns the address of the peer.
+func (c *WebSocketConn) RemoteAddr() net.Addr {
+	return c.addr
+}
+
+// LocalAddr returns the local address.
+func (c *WebSocketConn) LocalAddr() net.Addr {
+	return c.conn.LocalAddr()
+}
+
+// Close sends a close frame and closes the connection.
+func (c *WebSocketConn) Close() error {
+	if c.closed.Swap(true) {
+		return nil
+	}
+	// a stalled peer must not block closing
+	_ = c.conn.SetWriteDeadline(time.Now().Add(websocketCloseTimeout))
+	_ = c.writeClose(wsCloseNormal)
+	return c.conn.Close()
+}
+
+// maxPacketSize returns the maximum packet size of the connection.
+func (c *WebSocketConn) maxPacketSize() int {
+	if c.MaxPacketSize > 0 {
+		return c.MaxPacketSize
+	}
+	return DefaultMaxPacketSize
+}
+
+// WebSocketServer is an http.Handler, that accepts WebSocket connections
+// (e.g. of browsers) and dispatches the received OSC packets. It can share a
+// port with other handlers of an http.ServeMux.
+type WebSocketServer struct {
+	// Dispatcher dispatches the packets of all connections. The net.Addr of
+	// the handlers is a *WebSocketAddr.
+	Dispatcher Dispatcher
+	// MaxPacketSize is the maximum size of sent and received OSC packets in
+	// bytes. Zero means DefaultMaxPacketSize.
+	MaxPacketSize int
+	// DecodeOptions controls how received OSC packets are decoded.
+	DecodeOptions DecodeOptions
+	// CheckOrigin returns true if a connection with the Origin header of r is
+	// accepted. If it is nil, only requests without Origin header or with the
+	// host of the request as origin are accepted.
+	CheckOrigin func(r *http.Request) bool
+	// WriteQueueSize is the number of packets queued per connection. The
+	// packets are sent by a goroutine of the connection. If the queue is
+	// full, e.g. of a stalled browser, the connection is closed. Zero means
+	// DefaultWebSocketWriteQueueSize.
+	WriteQueueSize int
+
+	mutex    sync.Mutex
+	conns    map[*WebSocketConn]struct{}
+	closed   bool
+	watchers watchers
+}
+
+// NewWebSocketServer returns a WebSocketServer, that dispatches the received
+// packets with d.
+func NewWebSocketServer(d Dispatcher) *WebSocketServer {
+	return &WebSocketServer{Dispatcher: d}
+}
+
+// ServeHTTP implements the http.Handler interface. It upgrades the request to
+// a WebSocket connection and serves it until it is closed.
+func (s *WebSocketServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	closed := s.closed
+	s.mutex.Unlock()
+	if closed {
+		http.Error(w, "server closed", http.StatusServiceUnavailable)
+		return
+	}
+
+	c, err := s.upgrade(w, r)
+	if err != nil {
+		return
+	}
+	c.queue = make(chan []byte, s.writeQueueSize())
+
+	// the server may have been closed during the upgrade
+	s.mutex.Lock()
+	if s.closed {
+		s.mutex.Unlock()
+		c.Close()
+		return
+	}
+	if s.conns == nil {
+		s.conns = make(map[*WebSocketConn]struct{})
+	}
+	s.conns[c] = struct{}{}
+	s.mutex.Unlock()
+
+	done := make(chan struct{})
+	go c.writeQueue(done)
+
+	defer func() {
+		s.mutex.Lock()
+		delete(s.conns, c)
+		s.mutex.Unlock()
+		close(done)
+		c.Close()
+	}()
+
+	_ = c.serve(s.Dispatcher, &s.watchers)
+}
+
+// writeQueueSize returns the size of the write queue of a connection.
+func (s *WebSocketServer) writeQueueSize() int {
+	if s.WriteQueueSize <= 0 {
+		return DefaultWebSocketWriteQueueSize
+	}
+	return s.WriteQueueSize
+}
+
+// upgrade checks the opening handshake of a client and switches the
+// connection to the WebSocket protocol.
+func (s *WebSocketServer) upgrade(w http.ResponseWriter, r *http.Request) (*WebSocketConn, error) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return nil, ErrorWebSocketHandshake
+	}
+	if !headerContains(r.Header, "Connection", "upgrade") || !headerContains(r.Header, "Upgrade", "websocket") {
+		http.Error(w, "WebSocket upgrade required", http.StatusBadRequest)
+		return nil, ErrorWebSocketHandshake
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "unsupported WebSocket version", http.StatusUpgradeRequired)
+		return nil, ErrorWebSocketHandshake
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if nonce, err := base64.StdEncoding.DecodeString(key); err != nil || len(nonce) != 16 {
+		http.Error(w, "invalid Sec-WebSocket-Key", http.StatusBadRequest)
+		return nil, ErrorWebSocketHandshake
+	}
+	if !s.checkOrigin(r) {
+		http.Error(w, "origin not allowed", http.StatusForbidden)
+		return nil, ErrorWebSocketHandshake
+	}
+
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "WebSocket not supported", http.StatusInternalServerError)
+		return nil, ErrorNotSupported
+	}
+	conn, rw, err := hj.Hijack()
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = fmt.Fprintf(rw, "HTTP/1.1 101 Switching Protocols\r\n"+
+		"Upgrade: websocket\r\n"+
+		"Connection: Upgrade\r\n"+
+		"Sec-WebSocket-Accept: %s\r\n\r\n", websocketAccept(key))
+	if err == nil {
+		err = rw.Flush()
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	c := newWebSocketConn(conn, rw.Reader, false)
+	c.MaxPacketSize = s.MaxPacketSize
+	c.DecodeOptions = s.DecodeOptions
+	return c, nil
+}
+
+// checkOrigin returns true if the origin of r is accepted.
+func (s *WebSocketServer) checkOrigin(r *http.Request) bool {
+	if s.CheckOrigin != nil {
+		return s.CheckOrigin(r)
+	}
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, r.Host)
+}
+
+// Conns returns all open connections.
+func (s *WebSocketServer) Conns() []*WebSocketConn {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	conns := make([]*WebSocketConn, 0, len(s.conns))
+	for c := range s.conns {
+		conns = append(conns, c)
+	}
+	return conns
+}
+
+// SendTo queues an OSC Bundle or an OSC Message to the connection with the
+// address addr. If the queue of the connection is full, the connection is
+// closed and ErrorQueueFull is returned.
+func (s *WebSocketServer) SendTo(addr net.Addr, packet Packet) error {
+	for _, c := range s.Conns() {
+		if sameAddr(c.addr, addr) {
+			return c.Send(packet)
+		}
+	}
+	return ErrorOscAddress
+}
+
+// SendToAll queues an OSC Bundle or an OSC Message to all connections (see
+// SendTo). The packet is marshaled once. If sending fails for some
+// connections, SendErrors is returned.
+func (s *WebSocketServer) SendToAll(packet Packet) error {
+	data, err := packet.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	var errs SendErrors
+	for _, c := range s.Conns() {
+		if err := c.write(data); err != nil {
+			errs = append(errs, &DestinationError{Addr: c.addr, Err: err})
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// Bridge forwards all packets of the WebSocket connections to raddr using
+// node, and all packets node receives from raddr to all WebSocket
+// connections, e.g. to reach hardware from browsers. The node must be serving
+// (ListenAndServe), its packets are dispatched as usual. The returned function
+// stops forwarding.
+func (s *WebSocketServer) Bridge(node *Node, raddr string) (stop func(), err error) {
+	addr, err := node.resolve(raddr)
+	if err != nil {
+		return nil, err
+	}
+
+	removeWS := s.watchers.add(func(packet Packet, _ net.Addr) {
+		_ = node.SendToAddr(addr, packet)
+	})
+	removeNode := node.watchers.add(func(packet Packet, from net.Addr) {
+		if sameAddr(from, addr) {
+			_ = s.SendToAll(packet)
+		}
+	})
+
+	return func() {
+		removeWS()
+		removeNode()
+	}, nil
+}
+
+// Close closes all connections and rejects new ones.
+func (s *WebSocketServer) Close() {
+	s.mutex.Lock()
+	s.closed = true
+	s.mutex.Unlock()
+
+	for _, c := range s.Conns() {
+		c.Close()
+	}
+}
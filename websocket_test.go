@@ -0,0 +1,287 @@
+package osc_test
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"bekuba.de/go-osc"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebSocket(t *testing.T) {
+	addrs := make(chan net.Addr, 10)
+	d := osc.NewStandardDispatcher()
+	ws, server := newWebSocketServer(t, d)
+	err := d.AddMsgHandlerExt(ping, func(msg *osc.Message, addr net.Addr) {
+		addrs <- addr
+		err := ws.SendTo(addr, osc.NewMessage(pong, msg.Arguments...))
+		assert.NoError(t, err)
+	})
+	assert.NoError(t, err)
+
+	client, err := osc.DialWebSocket("ws" + strings.TrimPrefix(server.URL, "http") + "/osc")
+	assert.NoError(t, err)
+	defer client.Close()
+
+	err = client.SendMsg(ping, 1, "a")
+	assert.NoError(t, err)
+
+	select {
+	case addr := <-addrs:
+		assert.Equal(t, "ws", addr.Network())
+		assert.Equal(t, client.LocalAddr().String(), addr.String())
+	case <-time.After(time.Second):
+		t.Fatal("timeout")
+	}
+
+	p, err := client.Read()
+	assert.NoError(t, err)
+	assert.Equal(t, osc.NewMessage(pong, int32(1), "a"), p)
+
+	// broadcast
+	assert.Len(t, ws.Conns(), 1)
+	err = ws.SendToAll(osc.NewMessage("/meter", float32(0.5)))
+	assert.NoError(t, err)
+	p, err = client.Read()
+	assert.NoError(t, err)
+	assert.Equal(t, osc.NewMessage("/meter", float32(0.5)), p)
+
+	// the port is shared with other handlers
+	resp, err := http.Get(server.URL + "/")
+	assert.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, "hello", string(body))
+
+	// closed by the server
+	ws.Close()
+	_, err = client.Read()
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+func TestWebSocketHandshake(t *testing.T) {
+	_, server := newWebSocketServer(t, nil)
+
+	// not a WebSocket request
+	resp, err := http.Get(server.URL + "/osc")
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	// foreign origin
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/osc", nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	req.Header.Set("Origin", "http://example.com")
+	resp, err = http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	_, err = osc.DialWebSocket(server.URL + "/osc")
+	assert.ErrorIs(t, err, osc.ErrorOscAddressFormat)
+
+	_, err = osc.DialWebSocket("ws" + strings.TrimPrefix(server.URL, "http") + "/")
+	assert.ErrorIs(t, err, osc.ErrorWebSocketHandshake)
+}
+
+// rawFrame returns a masked client frame.
+func rawFrame(fin bool, opcode byte, payload []byte) []byte {
+	b0 := opcode
+	if fin {
+		b0 |= 0x80
+	}
+	key := [4]byte{1, 2, 3, 4}
+	frame := []byte{b0, 0x80 | byte(len(payload))}
+	frame = append(frame, key[:]...)
+	for i, b := range payload {
+		frame = append(frame, b^key[i%4])
+	}
+	return frame
+}
+
+func TestWebSocketFrames(t *testing.T) {
+	d, received := newMessageReceiver(t)
+	_, server := newWebSocketServer(t, d)
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(server.URL, "http://"))
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	// opening handshake of RFC 6455
+	fmt.Fprint(conn, "GET /osc HTTP/1.1\r\n"+
+		"Host: "+conn.RemoteAddr().String()+"\r\n"+
+		"Upgrade: websocket\r\n"+
+		"Connection: keep-alive, Upgrade\r\n"+
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n"+
+		"Sec-WebSocket-Version: 13\r\n\r\n")
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+	assert.Equal(t, "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=", resp.Header.Get("Sec-WebSocket-Accept"))
+
+	data, err := osc.NewMessage("/fragmented", int32(42)).MarshalBinary()
+	assert.NoError(t, err)
+
+	// fragmented packet with a ping in between
+	_, err = conn.Write(rawFrame(false, 0x2, data[:5]))
+	assert.NoError(t, err)
+	_, err = conn.Write(rawFrame(true, 0x9, []byte("hi")))
+	assert.NoError(t, err)
+	_, err = conn.Write(rawFrame(true, 0x0, data[5:]))
+	assert.NoError(t, err)
+
+	// pong
+	header := make([]byte, 4)
+	_, err = io.ReadFull(reader, header)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x8a, 2, 'h', 'i'}, header)
+
+	select {
+	case p := <-received:
+		assert.Equal(t, osc.NewMessage("/fragmented", int32(42)), p)
+	case <-time.After(time.Second):
+		t.Fatal("timeout")
+	}
+
+	// text frames are not supported
+	_, err = conn.Write(rawFrame(true, 0x1, []byte("/text")))
+	assert.NoError(t, err)
+	closeFrame := make([]byte, 4)
+	_, err = io.ReadFull(reader, closeFrame)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x88, 2}, closeFrame[:2])
+	assert.Equal(t, uint16(1003), binary.BigEndian.Uint16(closeFrame[2:]))
+}
+
+func TestWebSocketMaxPacketSize(t *testing.T) {
+	ws, server := newWebSocketServer(t, nil)
+	ws.MaxPacketSize = 64
+
+	client, err := osc.DialWebSocket("ws" + strings.TrimPrefix(server.URL, "http") + "/osc")
+	assert.NoError(t, err)
+	defer client.Close()
+
+	client.MaxPacketSize = 64
+	err = client.Send(osc.NewMessage("/blob", make([]byte, 128)))
+	assert.ErrorIs(t, err, osc.ErrorPacketTooLarge)
+
+	client.MaxPacketSize = 0
+	err = client.Send(osc.NewMessage("/blob", make([]byte, 128)))
+	assert.NoError(t, err)
+
+	// the server closes the connection
+	_, err = client.Read()
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+func TestWebSocketWriteQueue(t *testing.T) {
+	ws, server := newWebSocketServer(t, nil)
+	ws.WriteQueueSize = 2
+
+	// the client never reads
+	client, err := osc.DialWebSocket("ws" + strings.TrimPrefix(server.URL, "http") + "/osc")
+	assert.NoError(t, err)
+	defer client.Close()
+	assert.Eventually(t, func() bool { return len(ws.Conns()) == 1 }, time.Second, time.Millisecond)
+
+	// sending doesn't block, the stalled connection is closed
+	done := make(chan error)
+	go func() {
+		msg := osc.NewMessage("/blob", make([]byte, 60000))
+		for i := 0; i < 10000; i++ {
+			if err := ws.SendToAll(msg); err != nil {
+				done <- err
+				return
+			}
+		}
+		done <- nil
+	}()
+	select {
+	case err := <-done:
+		assert.ErrorIs(t, err, osc.ErrorQueueFull)
+	case <-time.After(5 * time.Second):
+		t.Fatal("SendToAll blocked")
+	}
+	assert.Eventually(t, func() bool { return len(ws.Conns()) == 0 }, time.Second, time.Millisecond)
+}
+
+func TestWebSocketServerClose(t *testing.T) {
+	ws, server := newWebSocketServer(t, nil)
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/osc"
+
+	client, err := osc.DialWebSocket(url)
+	assert.NoError(t, err)
+	defer client.Close()
+	assert.Eventually(t, func() bool { return len(ws.Conns()) == 1 }, time.Second, time.Millisecond)
+
+	ws.Close()
+	_, err = client.Read()
+	assert.ErrorIs(t, err, io.EOF)
+
+	// new connections are rejected
+	_, err = osc.DialWebSocket(url)
+	assert.ErrorIs(t, err, osc.ErrorWebSocketHandshake)
+	assert.Empty(t, ws.Conns())
+}
+
+func TestWebSocketBridge(t *testing.T) {
+	// hardware
+	device, err := osc.NewNode("127.0.0.1:0")
+	assert.NoError(t, err)
+	defer device.Close()
+	dd := osc.NewStandardDispatcher()
+	err = dd.AddMsgHandlerExt(ping, func(msg *osc.Message, addr net.Addr) {
+		err := device.SendMsgTo(addr.String(), pong)
+		assert.NoError(t, err)
+	})
+	assert.NoError(t, err)
+	go device.ListenAndServe(dd)
+
+	// bridge with its own dispatcher
+	node, err := osc.NewNode("127.0.0.1:0")
+	assert.NoError(t, err)
+	defer node.Close()
+	pongs := make(chan *osc.Message, 10)
+	nd := osc.NewStandardDispatcher()
+	err = nd.AddMsgHandler(pong, func(msg *osc.Message) {
+		pongs <- msg
+	})
+	assert.NoError(t, err)
+	go node.ListenAndServe(nd)
+
+	ws, server := newWebSocketServer(t, nil)
+	stop, err := ws.Bridge(node, device.Conn().LocalAddr().String())
+	assert.NoError(t, err)
+	defer stop()
+
+	browser, err := osc.DialWebSocket("ws" + strings.TrimPrefix(server.URL, "http") + "/osc")
+	assert.NoError(t, err)
+	defer browser.Close()
+
+	err = browser.SendMsg(ping)
+	assert.NoError(t, err)
+
+	p, err := browser.Read()
+	assert.NoError(t, err)
+	assert.Equal(t, osc.NewMessage(pong), p)
+
+	// the node dispatches the packets of the device as usual
+	select {
+	case msg := <-pongs:
+		assert.Equal(t, osc.NewMessage(pong), msg)
+	case <-time.After(time.Second):
+		t.Fatal("timeout")
+	}
+}